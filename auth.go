@@ -0,0 +1,462 @@
+package request
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Authenticator adds the credentials to the requests, for example, setting the `Authorization`
+// field in the request headers.
+//
+//	type tokenAuth struct{}
+//
+//	func (tokenAuth) Authenticate(req *http.Request) error {
+//	  req.Header.Set("X-Token", getToken())
+//	  return nil
+//	}
+//
+//	cli := request.New(request.Config{
+//	  Authenticator: tokenAuth{},
+//	})
+type Authenticator interface {
+	// Authenticate adds the credentials to the request before sending it. It'll be called again
+	// before re-sending the request for retrying or responding to the challenge.
+	Authenticate(req *http.Request) error
+}
+
+// ChallengeAuthenticator is an authenticator that can respond to the challenge of the server. If
+// the server responds with the 401 (Unauthorized) status code, the `Challenge` method will be
+// called with the response, and the request will be re-sent once with the credentials that are
+// added by the `Authenticate` method if the `Challenge` method returns true.
+type ChallengeAuthenticator interface {
+	Authenticator
+	// Challenge handles the 401 response of the request, and it returns true if the request should
+	// be re-sent with the new credentials.
+	Challenge(req *http.Request, resp *http.Response) (bool, error)
+}
+
+// Authenticate sets the username and the password as the HTTP Basic Auth to the request.
+func (auth *BasicAuthConfig) Authenticate(req *http.Request) error {
+	req.SetBasicAuth(auth.Username, auth.Password)
+
+	return nil
+}
+
+// BearerAuth is the authenticator that sets the token as the bearer token in the `Authorization`
+// field of the request headers.
+//
+//	resp, err := request.Request("https://example.com", request.RequestOptions{
+//	  Authenticator: &request.BearerAuth{Token: "XXXXX"},
+//	})
+type BearerAuth struct {
+	// Token is the bearer token.
+	Token string
+}
+
+// Authenticate sets the token as the bearer token to the request.
+func (auth *BearerAuth) Authenticate(req *http.Request) error {
+	req.Header.Set("Authorization", "Bearer "+auth.Token)
+
+	return nil
+}
+
+const (
+	// APIKeyInHeader indicates the API key will be sent as a field in the request headers.
+	APIKeyInHeader string = "header"
+	// APIKeyInQuery indicates the API key will be sent as a parameter in the query string.
+	APIKeyInQuery string = "query"
+)
+
+// APIKeyAuth is the authenticator that sends an API key in the request headers or the query
+// string.
+//
+//	cli := request.New(request.Config{
+//	  Authenticator: &request.APIKeyAuth{
+//	    Name:  "X-API-Key",
+//	    Value: "XXXXX",
+//	  },
+//	})
+type APIKeyAuth struct {
+	// Name is the name of the header field or the query parameter.
+	Name string
+	// Value is the API key.
+	Value string
+	// In indicates where to send the API key, available options are "header" and "query", default
+	// "header".
+	In string
+}
+
+// Authenticate adds the API key to the request headers or the query string.
+func (auth *APIKeyAuth) Authenticate(req *http.Request) error {
+	switch strings.ToLower(auth.In) {
+	case "", APIKeyInHeader:
+		req.Header.Set(auth.Name, auth.Value)
+	case APIKeyInQuery:
+		req.URL.RawQuery = setQueryParameter(req.URL.RawQuery, auth.Name, auth.Value)
+	default:
+		return fmt.Errorf("unsupported api key location %q", auth.In)
+	}
+
+	return nil
+}
+
+// setQueryParameter sets the value of the parameter in the raw query string. It replaces the
+// values of the parameter if it exists, or appends the parameter to the query string, and the
+// other parameters are kept as they are.
+func setQueryParameter(rawQuery, name, value string) string {
+	param := url.QueryEscape(name) + "=" + url.QueryEscape(value)
+	if rawQuery == "" {
+		return param
+	}
+
+	pairs := strings.Split(rawQuery, "&")
+	found := false
+	for i, pair := range pairs {
+		key, _, _ := strings.Cut(pair, "=")
+		if key, err := url.QueryUnescape(key); err == nil && key == name {
+			pairs[i] = param
+			found = true
+		}
+	}
+	if !found {
+		pairs = append(pairs, param)
+	}
+
+	return strings.Join(pairs, "&")
+}
+
+// DigestAuth is the authenticator for HTTP Digest Access Authentication (RFC 7616). The first
+// request will be sent without credentials, and it'll be re-sent with the digest credentials after
+// the server responds with the challenge. The following requests will use the same challenge
+// until the server responds with a new challenge.
+//
+// It supports the "MD5", "MD5-sess", "SHA-256", and "SHA-256-sess" algorithms, and the "auth" and
+// "auth-int" qualities of protection. The same `DigestAuth` should be used by pointer.
+//
+//	cli := request.New(request.Config{
+//	  Authenticator: &request.DigestAuth{
+//	    Username: "user",
+//	    Password: "pass",
+//	  },
+//	})
+type DigestAuth struct {
+	// Username indicates the username used for HTTP Digest Auth
+	Username string
+	// Password indicates the password used for HTTP Digest Auth
+	Password string
+
+	// challenge is the last challenge from the server.
+	challenge *digestChallenge
+	// nonceCount is the number of requests that are sent with the nonce of the challenge.
+	nonceCount uint32
+	// mutex is the locker for the challenge.
+	mutex sync.Mutex
+}
+
+// digestChallenge is the parameters of the digest challenge from the server.
+type digestChallenge struct {
+	realm     string
+	nonce     string
+	opaque    string
+	algorithm string
+	qop       string
+}
+
+// Authenticate sets the digest credentials to the request if the server has responded with a
+// challenge.
+func (auth *DigestAuth) Authenticate(req *http.Request) error {
+	auth.mutex.Lock()
+	challenge := auth.challenge
+	if challenge == nil {
+		auth.mutex.Unlock()
+		return nil
+	}
+	auth.nonceCount++
+	nonceCount := fmt.Sprintf("%08x", auth.nonceCount)
+	auth.mutex.Unlock()
+
+	cnonce, err := newDigestCnonce()
+	if err != nil {
+		return err
+	}
+
+	newHash := getDigestHashFunc(challenge.algorithm)
+	uri := req.URL.RequestURI()
+
+	ha1 := digestHash(newHash, auth.Username+":"+challenge.realm+":"+auth.Password)
+	if strings.HasSuffix(strings.ToLower(challenge.algorithm), "-sess") {
+		ha1 = digestHash(newHash, ha1+":"+challenge.nonce+":"+cnonce)
+	}
+
+	a2 := req.Method + ":" + uri
+	if challenge.qop == "auth-int" {
+		bodyHash, err := getDigestBodyHash(req, newHash)
+		if err != nil {
+			return err
+		}
+		a2 += ":" + bodyHash
+	}
+	ha2 := digestHash(newHash, a2)
+
+	var response string
+	if challenge.qop != "" {
+		response = digestHash(newHash, strings.Join([]string{
+			ha1, challenge.nonce, nonceCount, cnonce, challenge.qop, ha2,
+		}, ":"))
+	} else {
+		response = digestHash(newHash, ha1+":"+challenge.nonce+":"+ha2)
+	}
+
+	params := []string{
+		"username=" + quoteAuthParam(auth.Username),
+		"realm=" + quoteAuthParam(challenge.realm),
+		"nonce=" + quoteAuthParam(challenge.nonce),
+		"uri=" + quoteAuthParam(uri),
+		"algorithm=" + challenge.algorithm,
+		"response=" + quoteAuthParam(response),
+	}
+	if challenge.opaque != "" {
+		params = append(params, "opaque="+quoteAuthParam(challenge.opaque))
+	}
+	if challenge.qop != "" {
+		params = append(params, "qop="+challenge.qop, "nc="+nonceCount,
+			"cnonce="+quoteAuthParam(cnonce))
+	}
+
+	req.Header.Set("Authorization", "Digest "+strings.Join(params, ", "))
+
+	return nil
+}
+
+// Challenge parses the digest challenge from the response. It'll not re-send the request if the
+// request was sent with the credentials of a challenge that is not stale, which means the
+// credentials are rejected by the server.
+func (auth *DigestAuth) Challenge(req *http.Request, resp *http.Response) (bool, error) {
+	params, ok := getDigestChallengeParams(resp)
+	if !ok {
+		return false, nil
+	}
+
+	authorization := req.Header.Get("Authorization")
+	if strings.HasPrefix(authorization, "Digest ") && !strings.EqualFold(params["stale"], "true") {
+		return false, nil
+	}
+
+	challenge := &digestChallenge{
+		realm:     params["realm"],
+		nonce:     params["nonce"],
+		opaque:    params["opaque"],
+		algorithm: params["algorithm"],
+	}
+	if challenge.algorithm == "" {
+		challenge.algorithm = "MD5"
+	}
+	if getDigestHashFunc(challenge.algorithm) == nil {
+		return false, nil
+	}
+
+	if qop, ok := params["qop"]; ok {
+		for _, option := range strings.Split(qop, ",") {
+			option = strings.ToLower(strings.TrimSpace(option))
+			if option == "auth" || (option == "auth-int" && challenge.qop == "") {
+				challenge.qop = option
+			}
+		}
+		if challenge.qop == "" {
+			return false, nil
+		}
+	}
+
+	auth.mutex.Lock()
+	auth.challenge = challenge
+	auth.nonceCount = 0
+	auth.mutex.Unlock()
+
+	return true, nil
+}
+
+// quoteAuthParam quotes the value of the authentication parameter.
+func quoteAuthParam(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	return `"` + value + `"`
+}
+
+// getDigestChallengeParams finds the digest challenge in the `WWW-Authenticate` fields of the
+// response headers, and parses its parameters.
+func getDigestChallengeParams(resp *http.Response) (map[string]string, bool) {
+	for _, value := range resp.Header.Values("WWW-Authenticate") {
+		i := strings.Index(strings.ToLower(value), "digest ")
+		if i < 0 || (i > 0 && value[i-1] != ' ' && value[i-1] != ',') {
+			continue
+		}
+
+		return parseAuthParams(value[i+len("digest "):]), true
+	}
+
+	return nil, false
+}
+
+// parseAuthParams parses the comma-separated parameters of the authentication challenge, and it
+// stops at the next challenge.
+func parseAuthParams(s string) map[string]string {
+	params := make(map[string]string)
+
+	for {
+		s = strings.TrimLeft(s, " ,")
+		eq := strings.IndexByte(s, '=')
+		if eq <= 0 || strings.ContainsAny(s[:eq], " ,") {
+			return params
+		}
+
+		key := strings.ToLower(strings.TrimSpace(s[:eq]))
+		s = strings.TrimLeft(s[eq+1:], " ")
+
+		var value strings.Builder
+		if strings.HasPrefix(s, `"`) {
+			i := 1
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+				value.WriteByte(s[i])
+			}
+			if i < len(s) {
+				i++
+			}
+			s = s[i:]
+		} else {
+			end := strings.IndexByte(s, ',')
+			if end < 0 {
+				end = len(s)
+			}
+			value.WriteString(strings.TrimSpace(s[:end]))
+			s = s[end:]
+		}
+
+		params[key] = value.String()
+	}
+}
+
+// getDigestHashFunc returns the hash function of the digest algorithm, and it returns nil if the
+// algorithm is not supported.
+func getDigestHashFunc(algorithm string) func() hash.Hash {
+	switch strings.ToUpper(strings.TrimSuffix(strings.ToLower(algorithm), "-sess")) {
+	case "MD5":
+		return md5.New
+	case "SHA-256":
+		return sha256.New
+	default:
+		return nil
+	}
+}
+
+// digestHash returns the hex-encoded hash of the data.
+func digestHash(newHash func() hash.Hash, data string) string {
+	h := newHash()
+	h.Write([]byte(data))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// getDigestBodyHash returns the hex-encoded hash of the request body for the "auth-int" quality
+// of protection, and the body is read by the `GetBody` function of the request.
+func getDigestBodyHash(req *http.Request, newHash func() hash.Hash) (string, error) {
+	h := newHash()
+
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody == nil {
+			return "", ErrBodyNotReplayable
+		}
+
+		body, err := req.GetBody()
+		if err != nil {
+			return "", err
+		}
+		defer body.Close()
+
+		if _, err := io.Copy(h, body); err != nil {
+			return "", err
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// newDigestCnonce generates a random client nonce.
+func newDigestCnonce() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(buf), nil
+}
+
+// getAuthenticator returns the authenticator from the request options or the client config. The
+// `Authenticator` field in the request options takes precedence over the `Auth` field.
+func (cli *Client) getAuthenticator(opt RequestOptions) Authenticator {
+	if opt.Authenticator != nil {
+		return opt.Authenticator
+	} else if opt.Auth != nil {
+		return opt.Auth
+	}
+
+	return cli.Authenticator
+}
+
+// authenticate adds the credentials to the request by the authenticator of the request.
+func (cli *Client) authenticate(req *http.Request, opt RequestOptions) error {
+	auth := cli.getAuthenticator(opt)
+	if auth == nil {
+		return nil
+	}
+
+	return auth.Authenticate(req)
+}
+
+// respondAuthChallenge tries to respond to the challenge of the server if the status code of the
+// response is 401 and the authenticator supports challenges. It returns true if the credentials
+// are updated and the request is ready to be re-sent.
+func (cli *Client) respondAuthChallenge(
+	req *http.Request,
+	resp *http.Response,
+	opt RequestOptions,
+) (bool, error) {
+	if resp.StatusCode != http.StatusUnauthorized || !isRequestBodyReplayable(req) {
+		return false, nil
+	}
+
+	auth, ok := cli.getAuthenticator(opt).(ChallengeAuthenticator)
+	if !ok {
+		return false, nil
+	}
+
+	retry, err := auth.Challenge(req, resp)
+	if err != nil {
+		discardResponse(resp)
+		return false, err
+	} else if !retry {
+		return false, nil
+	}
+
+	discardResponse(resp)
+
+	if err := rewindRequestBody(req); err != nil {
+		return false, err
+	}
+
+	if err := auth.Authenticate(req); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
@@ -0,0 +1,161 @@
+package request
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ghosind/go-assert"
+)
+
+func TestClientAuthenticator(t *testing.T) {
+	a := assert.New(t)
+
+	cli := New(Config{
+		Authenticator: &BasicAuthConfig{Username: "user", Password: "pass"},
+	})
+
+	data, _, err := ToObject[testResponse](cli.GET("http://127.0.0.1:8080/test"))
+	a.NilNow(err)
+	a.EqualNow(*data.Token, "Basic dXNlcjpwYXNz")
+
+	// the request options take precedence over the client config
+	data, _, err = ToObject[testResponse](cli.Req("http://127.0.0.1:8080/test").
+		SetBasicAuth("admin", "secret").
+		Do())
+	a.NilNow(err)
+	a.EqualNow(*data.Token, "Basic YWRtaW46c2VjcmV0")
+
+	data, _, err = ToObject[testResponse](cli.Req("http://127.0.0.1:8080/test").
+		SetBasicAuth("admin", "secret").
+		SetBearerToken("token").
+		Do())
+	a.NilNow(err)
+	a.EqualNow(*data.Token, "Bearer token")
+}
+
+func TestBearerAuth(t *testing.T) {
+	a := assert.New(t)
+
+	data, _, err := ToObject[testResponse](GET("http://127.0.0.1:8080/test", RequestOptions{
+		Authenticator: &BearerAuth{Token: "XXXXX"},
+	}))
+	a.NilNow(err)
+	a.EqualNow(*data.Token, "Bearer XXXXX")
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	a := assert.New(t)
+
+	data, _, err := ToObject[testResponse](Req("http://127.0.0.1:8080/test").
+		SetAuthenticator(&APIKeyAuth{Name: "X-API-Key", Value: "XXXXX"}).
+		Do())
+	a.NilNow(err)
+	a.EqualNow((*data.Headers)["X-Api-Key"], []string{"XXXXX"})
+
+	data, _, err = ToObject[testResponse](Req("http://127.0.0.1:8080/test").
+		AddParameter("q", "test").
+		SetAuthenticator(&APIKeyAuth{Name: "api_key", Value: "a b", In: APIKeyInQuery}).
+		Do())
+	a.NilNow(err)
+	a.EqualNow(*data.Query, "q=test&api_key=a+b")
+
+	data, _, err = ToObject[testResponse](Req("http://127.0.0.1:8080/test").
+		AddParameter("api_key", "old").
+		SetAuthenticator(&APIKeyAuth{Name: "api_key", Value: "new", In: APIKeyInQuery}).
+		Do())
+	a.NilNow(err)
+	a.EqualNow(*data.Query, "api_key=new")
+
+	// keep the query string that is serialized by the custom serializer
+	cli := New(Config{
+		ParametersSerializer: func(map[string][]string) string {
+			return "z=1&api_key=old&a=x%20y"
+		},
+		Authenticator: &APIKeyAuth{Name: "api_key", Value: "new", In: APIKeyInQuery},
+	})
+	data, _, err = ToObject[testResponse](cli.GET("http://127.0.0.1:8080/test", RequestOptions{
+		Parameters: map[string][]string{"z": {"1"}},
+	}))
+	a.NilNow(err)
+	a.EqualNow(*data.Query, "z=1&api_key=new&a=x%20y")
+
+	_, err = Req("http://127.0.0.1:8080/test").
+		SetAuthenticator(&APIKeyAuth{Name: "api_key", Value: "XXXXX", In: "cookie"}).
+		Do()
+	a.NotNilNow(err)
+}
+
+func TestDigestAuth(t *testing.T) {
+	a := assert.New(t)
+
+	for _, tc := range []struct {
+		algorithm string
+		qop       string
+	}{
+		{"MD5", ""},
+		{"MD5", "auth"},
+		{"MD5-sess", "auth"},
+		{"SHA-256", "auth"},
+		{"SHA-256-sess", "auth-int"},
+		{"MD5", "auth-int,auth"},
+	} {
+		auth := &DigestAuth{Username: "user", Password: "pass"}
+		url := "http://127.0.0.1:8080/digest?algorithm=" + tc.algorithm + "&qop=" + tc.qop
+		cli := New(Config{Authenticator: auth})
+
+		for i := 0; i < 2; i++ {
+			data, resp, err := ToObject[testResponse](cli.POST(url, RequestOptions{
+				Body: map[string]any{"data": "Hello world"},
+			}))
+			a.NilNow(err)
+			a.EqualNow(resp.StatusCode, http.StatusOK)
+			a.TrueNow(strings.HasPrefix(*data.Token, "Digest "))
+			a.EqualNow(*data.Body, `{"data":"Hello world"}`)
+		}
+	}
+}
+
+func TestDigestAuthWithInvalidCredentials(t *testing.T) {
+	a := assert.New(t)
+
+	_, err := GET("http://127.0.0.1:8080/digest?qop=auth", RequestOptions{
+		Authenticator: &DigestAuth{Username: "user", Password: "wrong"},
+	})
+	a.NotNilNow(err)
+
+	var respErr *ResponseError
+	a.TrueNow(errors.As(err, &respErr))
+	a.EqualNow(respErr.StatusCode, http.StatusUnauthorized)
+
+	// not a digest challenge
+	_, err = GET("http://127.0.0.1:8080/status?status=401", RequestOptions{
+		Authenticator: &DigestAuth{Username: "user", Password: "pass"},
+	})
+	a.TrueNow(errors.As(err, &respErr))
+	a.EqualNow(respErr.StatusCode, http.StatusUnauthorized)
+
+	// unsupported qop
+	_, err = GET("http://127.0.0.1:8080/digest?qop=unknown", RequestOptions{
+		Authenticator: &DigestAuth{Username: "user", Password: "pass"},
+	})
+	a.TrueNow(errors.As(err, &respErr))
+	a.EqualNow(respErr.StatusCode, http.StatusUnauthorized)
+}
+
+func TestParseAuthParams(t *testing.T) {
+	a := assert.New(t)
+
+	params := parseAuthParams(`realm="a \"b\", c", nonce=abc, qop="auth,auth-int" , ` +
+		`Basic realm="basic"`)
+	a.EqualNow(params, map[string]string{
+		"realm": `a "b", c`,
+		"nonce": "abc",
+		"qop":   "auth,auth-int",
+	})
+
+	a.EqualNow(parseAuthParams(`realm="unclosed`), map[string]string{"realm": "unclosed"})
+	a.EqualNow(parseAuthParams(""), map[string]string{})
+	a.EqualNow(quoteAuthParam(`a "b" \c`), `"a \"b\" \\c"`)
+}
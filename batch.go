@@ -0,0 +1,233 @@
+package request
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// BatchRequest is a request of a batch, with the URL and the request options.
+type BatchRequest struct {
+	// URL is the destination of the request.
+	URL string
+	// Options are the request options, and the request will be sent as an HTTP GET request if no
+	// method is set. The context of the batch is used as the parent of the request context if no
+	// context is set in the options.
+	Options RequestOptions
+}
+
+// BatchOptions defines how to execute the requests of a batch.
+type BatchOptions struct {
+	// Concurrency is the maximum number of the requests that are executed at the same time, and all
+	// requests will be executed at the same time if it's less than or equal to 0.
+	Concurrency int
+	// FailFast indicates whether to cancel the remaining requests when a request fails. The
+	// requests that are not started or in-flight will fail with `context.Canceled`. It'll wait for
+	// all requests by default.
+	FailFast bool
+}
+
+// BatchResult is the result of a request of a batch.
+type BatchResult struct {
+	// Response is the response of the request, and its body should be closed by the caller.
+	Response *http.Response
+	// Err is the error of the request.
+	Err error
+}
+
+// BatchObjectResult is the result of a request of a batch, with the response body that is decoded
+// to an object.
+type BatchObjectResult[T any] struct {
+	// Data is the decoded response body.
+	Data *T
+	// Response is the response of the request, and its body is already closed.
+	Response *http.Response
+	// Err is the error of the request or decoding the response body.
+	Err error
+}
+
+// DoAll executes the requests with the concurrency of the batch options, and returns the results
+// in the same order as the requests. It returns the first error that occurred, and the results
+// have the errors of every request. The requests that are not started will not be sent if the
+// context is canceled. The bodies of the responses in the results should be closed.
+//
+//	results, err := cli.DoAll(ctx, []request.BatchRequest{
+//	  {URL: "https://example.com/users/1"},
+//	  {URL: "https://example.com/users/2"},
+//	}, request.BatchOptions{Concurrency: 2, FailFast: true})
+func (cli *Client) DoAll(
+	ctx context.Context,
+	requests []BatchRequest,
+	opts ...BatchOptions,
+) ([]BatchResult, error) {
+	results := make([]BatchResult, len(requests))
+
+	err := cli.doAll(ctx, requests, opts, func(i int, resp *http.Response, err error) error {
+		results[i] = BatchResult{Response: resp, Err: err}
+		return err
+	})
+
+	return results, err
+}
+
+// DoAllTo executes the requests by the client like `DoAll`, and decodes the response bodies to
+// the objects as the parameter type like `ToObject`. It uses the default client if the client is
+// nil. A request fails if it fails to decode the response body, and it'll cancel the remaining
+// requests in the fail-fast mode.
+//
+//	results, err := request.DoAllTo[User](ctx, cli, []request.BatchRequest{
+//	  {URL: "https://example.com/users/1"},
+//	  {URL: "https://example.com/users/2"},
+//	})
+func DoAllTo[T any](
+	ctx context.Context,
+	cli *Client,
+	requests []BatchRequest,
+	opts ...BatchOptions,
+) ([]BatchObjectResult[T], error) {
+	if cli == nil {
+		cli = defaultClient
+	}
+	results := make([]BatchObjectResult[T], len(requests))
+
+	err := cli.doAll(ctx, requests, opts, func(i int, resp *http.Response, err error) error {
+		if err != nil {
+			closeResponseBody(resp)
+			results[i] = BatchObjectResult[T]{Response: resp, Err: err}
+			return err
+		}
+
+		data, resp, err := ToObject[T](resp, nil)
+		results[i] = BatchObjectResult[T]{Data: data, Response: resp, Err: err}
+		return err
+	})
+
+	return results, err
+}
+
+// doAll executes the requests by the workers, and calls the handler with the response and the
+// error of each request in the worker. The handler returns the error of the request, and it'll
+// cancel the remaining requests in the fail-fast mode if the error is not nil.
+func (cli *Client) doAll(
+	ctx context.Context,
+	requests []BatchRequest,
+	opts []BatchOptions,
+	handle func(i int, resp *http.Response, err error) error,
+) error {
+	var opt BatchOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	concurrency := opt.Concurrency
+	if concurrency <= 0 || concurrency > len(requests) {
+		concurrency = len(requests)
+	}
+
+	b := &batch{
+		cli:     cli,
+		ctx:     ctx,
+		opt:     opt,
+		cancels: make(map[int]context.CancelFunc),
+	}
+
+	indexes := make(chan int)
+	wg := sync.WaitGroup{}
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				resp, err := b.execute(i, requests[i])
+				b.done(handle(i, resp, err))
+			}
+		}()
+	}
+
+	for i := range requests {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	return b.err
+}
+
+// batch is the state of executing a batch.
+type batch struct {
+	cli *Client
+	ctx context.Context
+	opt BatchOptions
+	// err is the first error of the requests.
+	err error
+	// failed indicates whether the remaining requests are canceled in the fail-fast mode.
+	failed bool
+	// cancels are the functions to cancel the in-flight requests.
+	cancels map[int]context.CancelFunc
+	// mutex is the locker for the state.
+	mutex sync.Mutex
+}
+
+// execute sends the request with a cancelable context, and the context will be canceled when the
+// response body is closed.
+func (b *batch) execute(i int, req BatchRequest) (*http.Response, error) {
+	opt := req.Options
+
+	parent, cancelTimeout := opt.Context, func() {}
+	if parent == nil {
+		parent, cancelTimeout = b.cli.withTimeout(b.ctx, opt)
+	}
+	ctx, cancel := context.WithCancel(parent)
+	cancelAll := func() {
+		cancel()
+		cancelTimeout()
+	}
+
+	b.mutex.Lock()
+	if b.failed {
+		b.mutex.Unlock()
+		cancelAll()
+		return nil, context.Canceled
+	} else if err := b.ctx.Err(); err != nil {
+		b.mutex.Unlock()
+		cancelAll()
+		return nil, err
+	}
+	b.cancels[i] = cancelAll
+	b.mutex.Unlock()
+
+	opt.Context = ctx
+	resp, err := b.cli.Request(req.URL, opt)
+
+	// the request is completed, and it'll not be canceled by the failures of other requests.
+	b.mutex.Lock()
+	delete(b.cancels, i)
+	b.mutex.Unlock()
+	callOnBodyClose(resp, cancelAll)
+
+	return resp, err
+}
+
+// done records the error of the request, and cancels the in-flight requests if the request fails
+// in the fail-fast mode.
+func (b *batch) done(err error) {
+	if err == nil {
+		return
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.err == nil {
+		b.err = err
+	}
+	if b.opt.FailFast && !b.failed {
+		b.failed = true
+		for _, cancel := range b.cancels {
+			cancel()
+		}
+	}
+}
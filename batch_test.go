@@ -0,0 +1,178 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ghosind/go-assert"
+)
+
+func TestDoAll(t *testing.T) {
+	a := assert.New(t)
+
+	requests := make([]BatchRequest, 0, 5)
+	for i := 0; i < 5; i++ {
+		requests = append(requests, BatchRequest{
+			URL: fmt.Sprintf("http://127.0.0.1:8080/delay?ms=%d&index=%d", 40-i*10, i),
+		})
+	}
+	requests = append(requests, BatchRequest{
+		URL:     "http://127.0.0.1:8080/test",
+		Options: RequestOptions{Method: http.MethodPost, Body: "Hello"},
+	})
+
+	results, err := DoAll(context.Background(), requests)
+	a.NilNow(err)
+	a.EqualNow(len(results), 6)
+
+	for i, result := range results[:5] {
+		data, _, err := ToObject[testResponse](result.Response, result.Err)
+		a.NilNow(err)
+		a.EqualNow(*data.Query, fmt.Sprintf("index=%d&ms=%d", i, 40-i*10))
+	}
+
+	data, _, err := ToObject[testResponse](results[5].Response, results[5].Err)
+	a.NilNow(err)
+	a.EqualNow(*data.Method, http.MethodPost)
+	a.EqualNow(*data.Body, "Hello")
+}
+
+func TestDoAllWithConcurrency(t *testing.T) {
+	a := assert.New(t)
+
+	running := atomic.Int64{}
+	maxRunning := atomic.Int64{}
+	cli := New()
+	cli.UseRequestInterceptor(func(req *http.Request) error {
+		n := running.Add(1)
+		for {
+			old := maxRunning.Load()
+			if n <= old || maxRunning.CompareAndSwap(old, n) {
+				break
+			}
+		}
+		return nil
+	})
+	cli.UseResponseInterceptor(func(resp *http.Response) error {
+		running.Add(-1)
+		return nil
+	})
+
+	requests := make([]BatchRequest, 6)
+	for i := range requests {
+		requests[i] = BatchRequest{URL: "http://127.0.0.1:8080/delay?ms=20"}
+	}
+
+	results, err := cli.DoAll(context.Background(), requests, BatchOptions{Concurrency: 2})
+	a.NilNow(err)
+	for _, result := range results {
+		a.NilNow(result.Err)
+		result.Response.Body.Close()
+	}
+	a.EqualNow(maxRunning.Load(), int64(2))
+}
+
+func TestDoAllCollectAll(t *testing.T) {
+	a := assert.New(t)
+
+	results, err := DoAll(context.Background(), []BatchRequest{
+		{URL: "http://127.0.0.1:8080/status?status=500"},
+		{URL: "http://127.0.0.1:8080/delay?ms=50"},
+	})
+	a.NotNilNow(err)
+	a.EqualNow(err, results[0].Err)
+	a.NotNilNow(results[0].Response)
+	a.EqualNow(results[0].Response.StatusCode, http.StatusInternalServerError)
+	results[0].Response.Body.Close()
+
+	// the body of the other response is still readable
+	data, _, err := ToObject[testResponse](results[1].Response, results[1].Err)
+	a.NilNow(err)
+	a.EqualNow(*data.Path, "/delay")
+}
+
+func TestDoAllFailFast(t *testing.T) {
+	a := assert.New(t)
+
+	start := time.Now()
+	results, err := DoAll(context.Background(), []BatchRequest{
+		{URL: "http://127.0.0.1:8080/test"},
+		{URL: "http://127.0.0.1:8080/delay?ms=1000"},
+		{URL: "http://127.0.0.1:8080/delay?ms=10"},
+		{URL: "http://127.0.0.1:8080/delay?ms=50&status=400"},
+		{URL: "http://127.0.0.1:8080/test"},
+	}, BatchOptions{Concurrency: 2, FailFast: true})
+	a.TrueNow(time.Since(start) < 500*time.Millisecond)
+	a.NotNilNow(err)
+	a.NotTrueNow(errors.Is(err, context.Canceled))
+	a.EqualNow(err, results[3].Err)
+
+	// the completed response is not affected
+	data, _, err := ToObject[testResponse](results[0].Response, results[0].Err)
+	a.NilNow(err)
+	a.EqualNow(*data.Path, "/test")
+
+	a.TrueNow(errors.Is(results[1].Err, context.Canceled))
+	a.TrueNow(errors.Is(results[4].Err, context.Canceled))
+	a.NilNow(results[4].Response)
+}
+
+func TestDoAllFailFastCompletedRequest(t *testing.T) {
+	a := assert.New(t)
+
+	results, err := DoAllTo[[]testStreamElement](context.Background(), nil, []BatchRequest{
+		{URL: "http://127.0.0.1:8080/stream?count=5&interval=20"},
+		{URL: "http://127.0.0.1:8080/delay?ms=20&status=400"},
+	}, BatchOptions{FailFast: true})
+	a.NotNilNow(err)
+	a.EqualNow(err, results[1].Err)
+
+	// the body of the completed request is not canceled by the failure
+	a.NilNow(results[0].Err)
+	a.EqualNow(len(*results[0].Data), 5)
+}
+
+func TestDoAllWithCanceledContext(t *testing.T) {
+	a := assert.New(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	results, err := DoAll(ctx, []BatchRequest{
+		{URL: "http://127.0.0.1:8080/delay?ms=1000"},
+		{URL: "http://127.0.0.1:8080/test"},
+	}, BatchOptions{Concurrency: 1})
+	a.TrueNow(errors.Is(err, context.DeadlineExceeded))
+	a.TrueNow(errors.Is(results[0].Err, context.DeadlineExceeded))
+	a.TrueNow(errors.Is(results[1].Err, context.DeadlineExceeded))
+
+	results, err = DoAll(context.Background(), nil)
+	a.NilNow(err)
+	a.EqualNow(len(results), 0)
+}
+
+func TestDoAllTo(t *testing.T) {
+	a := assert.New(t)
+
+	results, err := DoAllTo[testResponse](context.Background(), nil, []BatchRequest{
+		{URL: "http://127.0.0.1:8080/test?index=0"},
+		{URL: "http://127.0.0.1:8080/status?status=404"},
+		{URL: "http://127.0.0.1:8080/test?index=2", Options: RequestOptions{Method: "PUT"}},
+	}, BatchOptions{Concurrency: 2})
+	a.NotNilNow(err)
+
+	a.NilNow(results[0].Err)
+	a.EqualNow(*results[0].Data.Query, "index=0")
+
+	a.EqualNow(results[1].Err, err)
+	a.NilNow(results[1].Data)
+	a.EqualNow(results[1].Response.StatusCode, http.StatusNotFound)
+
+	a.NilNow(results[2].Err)
+	a.EqualNow(*results[2].Data.Method, "PUT")
+}
@@ -2,7 +2,6 @@ package request
 
 import (
 	"bytes"
-	"encoding/json"
 	"io"
 	"strings"
 )
@@ -10,13 +9,27 @@ import (
 const (
 	// RequestContentTypeJSON indicates the request body encodes as a JSON.
 	RequestContentTypeJSON string = "json"
+	// RequestContentTypeForm indicates the request body encodes as a form-urlencoded string.
+	RequestContentTypeForm string = "form"
+	// RequestContentTypeMultipart indicates the request body encodes as a multipart form, and the
+	// parts will be streamed to the server.
+	RequestContentTypeMultipart string = "multipart"
+	// RequestContentTypeXML indicates the request body encodes as a XML.
+	RequestContentTypeXML string = "xml"
 )
 
 // getRequestBody returns the encoded request body as an io.Reader object. The function will try
 // to get a supported content type from the Header field in the request config, and it will try to
 // serialize the data as a JSON string if no content type or the content type is unsupported.
 // It'll skip encoding the request body if it's a nil pointer, a string, or a slice of bytes.
+//
+// For the multipart content type, it returns a reader that streams the parts of the multipart
+// form instead of encoding the whole body into memory.
 func (cli *Client) getRequestBody(opt RequestOptions) (io.Reader, error) {
+	if strings.ToLower(opt.ContentType) == RequestContentTypeMultipart {
+		return cli.getMultipartBody(opt)
+	}
+
 	body := opt.Body
 	if body == nil {
 		return nil, nil
@@ -31,8 +44,9 @@ func (cli *Client) getRequestBody(opt RequestOptions) (io.Reader, error) {
 }
 
 // encodeRequestBody encodes the request body by the specific encoder. It'll get the encoding
-// type from the 'ContentType' field in the request config, and it uses JSON as the default
-// encoder. It will skip encoding the request body data if it is a byte array slice or a string.
+// type from the 'ContentType' field in the request config, and find the codec with the same name
+// from the client's codecs or the package-level codecs. It uses JSON as the default encoder, and
+// it will skip encoding the request body data if it is a byte array slice or a string.
 func (cli *Client) encodeRequestBody(body any, contentType string) ([]byte, error) {
 	if body == nil {
 		return nil, nil
@@ -45,14 +59,14 @@ func (cli *Client) encodeRequestBody(body any, contentType string) ([]byte, erro
 		return []byte(v), nil
 	}
 
-	var handler func(any) ([]byte, error)
+	if contentType == "" {
+		contentType = RequestContentTypeJSON
+	}
 
-	switch strings.ToLower(contentType) {
-	case RequestContentTypeJSON, "":
-		handler = json.Marshal
-	default:
+	codec, ok := cli.getCodec(contentType)
+	if !ok {
 		return nil, ErrUnsupportedType
 	}
 
-	return handler(body)
+	return codec.Marshal(body)
 }
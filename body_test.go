@@ -48,6 +48,21 @@ func TestEncodeRequestBody(t *testing.T) {
 	a.NotNilNow(err)
 }
 
+func TestEncodeXMLRequestBody(t *testing.T) {
+	a := assert.New(t)
+	cli := New()
+
+	type testStruct struct {
+		XMLName struct{} `xml:"greeting"`
+		Message string   `xml:"message"`
+	}
+	out, err := cli.encodeRequestBody(testStruct{
+		Message: "Hello",
+	}, RequestContentTypeXML)
+	a.NilNow(err)
+	a.EqualNow(string(out), "<greeting><message>Hello</message></greeting>")
+}
+
 func testEncodeRequestBody(a *assert.Assertion, cli *Client, data any, expect []byte) {
 	out, err := cli.encodeRequestBody(data, "")
 	a.NilNow(err)
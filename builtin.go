@@ -1,6 +1,9 @@
 package request
 
-import "net/http"
+import (
+	"context"
+	"net/http"
+)
 
 // defaultClient is the default HTTP client for sending requests without creating a new client
 // object.
@@ -22,6 +25,28 @@ func Request(url string, opts ...RequestOptions) (*http.Response, error) {
 	return defaultClient.Request(url, opts...)
 }
 
+// DoAll executes the requests by the default client with the concurrency of the batch options, and
+// returns the results in the same order as the requests.
+func DoAll(
+	ctx context.Context,
+	requests []BatchRequest,
+	opts ...BatchOptions,
+) ([]BatchResult, error) {
+	return defaultClient.DoAll(ctx, requests, opts...)
+}
+
+// Download downloads the file of the URL into the path by the default client, and returns the size
+// of the file.
+func Download(url, path string, opts ...DownloadOptions) (int64, error) {
+	return defaultClient.Download(url, path, opts...)
+}
+
+// SSE connects to the Server-Sent Events stream of the URL by the default client, and returns the
+// stream to receive the events.
+func SSE(url string, opts ...RequestOptions) *SSEStream {
+	return defaultClient.SSE(url, opts...)
+}
+
 // DELETE performs an HTTP DELETE request to the specific URL with the request options.
 func DELETE(url string, opt ...RequestOptions) (*http.Response, error) {
 	return defaultClient.DELETE(url, opt...)
@@ -3,6 +3,7 @@ package request
 import (
 	"context"
 	"encoding/json"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
@@ -10,6 +11,7 @@ import (
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/ghosind/go-assert"
 	"github.com/ghosind/go-request/internal"
@@ -22,12 +24,27 @@ func TestMain(m *testing.M) {
 	proxy := internal.NewProxyServer()
 	go proxy.Run()
 
+	waitForServer("127.0.0.1:8080")
+	waitForServer("127.0.0.1:8000")
+
 	status := m.Run()
 
 	server.Shutdown()
 	os.Exit(status)
 }
 
+// waitForServer waits until the server is listening on the address.
+func waitForServer(addr string) {
+	for i := 0; i < 100; i++ {
+		conn, err := net.Dial("tcp", addr)
+		if err == nil {
+			conn.Close()
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
 type testResponse struct {
 	Path        *string              `json:"path"`
 	Method      *string              `json:"method"`
@@ -0,0 +1,208 @@
+package request
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// BulkheadConfig defines the limits of the concurrent in-flight requests of a client, for all
+// the hosts and for each host. A request takes a slot before sending, and the slot will be
+// released when the response body is closed or it fails to send the request, so the response
+// body should always be closed. The requests will wait in the queue if no slot is available, and
+// they'll fail with an `ErrBulkheadFull` error if the queue is full.
+//
+//	cli := request.New(request.Config{
+//	  Bulkhead: &request.BulkheadConfig{
+//	    MaxConcurrent:        100,
+//	    MaxConcurrentPerHost: 10,
+//	    MaxQueue:             1000,
+//	  },
+//	})
+type BulkheadConfig struct {
+	// MaxConcurrent is the maximum number of the in-flight requests for all the hosts, no limit if
+	// it's less than or equal to 0.
+	MaxConcurrent int
+	// MaxConcurrentPerHost is the maximum number of the in-flight requests for each host, no limit
+	// if it's less than or equal to 0.
+	MaxConcurrentPerHost int
+	// MaxQueue is the maximum number of the requests that are waiting for the slots of a limit,
+	// no limit if it's 0. The requests will be rejected immediately when no slot is available if
+	// it's negative.
+	MaxQueue int
+}
+
+// BulkheadStats is the statistics of the bulkhead of a client.
+type BulkheadStats struct {
+	// InFlight is the number of the requests that hold the slots for all the hosts.
+	InFlight int64
+	// Waiting is the number of the requests that are waiting for the slots.
+	Waiting int64
+	// Rejected is the number of the requests that failed because the queue is full.
+	Rejected int64
+}
+
+// bulkhead limits the concurrent in-flight requests of a client.
+type bulkhead struct {
+	// config is the limits of the bulkhead.
+	config BulkheadConfig
+	// global is the semaphore for all the requests.
+	global *semaphore
+	// hosts are the semaphores for the hosts.
+	hosts map[string]*semaphore
+	// mutex is the locker for the hosts.
+	mutex sync.Mutex
+
+	inFlight atomic.Int64
+	waiting  atomic.Int64
+	rejected atomic.Int64
+}
+
+// semaphore is a counting semaphore with a bounded queue.
+type semaphore struct {
+	// slots are the taken slots.
+	slots chan struct{}
+	// maxQueue is the maximum number of the waiting requests.
+	maxQueue int
+	// waiting is the number of the waiting requests.
+	waiting atomic.Int64
+}
+
+// newBulkhead creates a bulkhead by the config, and it returns nil if the config has no limit.
+func newBulkhead(config *BulkheadConfig) *bulkhead {
+	if config == nil || (config.MaxConcurrent <= 0 && config.MaxConcurrentPerHost <= 0) {
+		return nil
+	}
+
+	bh := &bulkhead{
+		config: *config,
+		hosts:  make(map[string]*semaphore),
+	}
+	if config.MaxConcurrent > 0 {
+		bh.global = newSemaphore(config.MaxConcurrent, config.MaxQueue)
+	}
+
+	return bh
+}
+
+// newSemaphore creates a semaphore with the number of the slots and the maximum queue length.
+func newSemaphore(size, maxQueue int) *semaphore {
+	return &semaphore{
+		slots:    make(chan struct{}, size),
+		maxQueue: maxQueue,
+	}
+}
+
+// BulkheadStats returns the statistics of the client's bulkhead, and it returns the zero value if
+// no bulkhead is set.
+func (cli *Client) BulkheadStats() BulkheadStats {
+	bh := cli.bulkhead
+	if bh == nil {
+		return BulkheadStats{}
+	}
+
+	return BulkheadStats{
+		InFlight: bh.inFlight.Load(),
+		Waiting:  bh.waiting.Load(),
+		Rejected: bh.rejected.Load(),
+	}
+}
+
+// acquireBulkhead takes the slots of the client's bulkhead for the request, and it returns the
+// function to release the slots.
+func (cli *Client) acquireBulkhead(req *http.Request) (func(), error) {
+	if cli.bulkhead == nil {
+		return func() {}, nil
+	}
+
+	return cli.bulkhead.acquire(req.Context(), req.URL.Host)
+}
+
+// acquire takes the slots of the host's semaphore and the global semaphore. It takes the host's
+// slot first, so the requests that are waiting for a busy host don't hold the global slots.
+func (bh *bulkhead) acquire(ctx context.Context, host string) (func(), error) {
+	sems := make([]*semaphore, 0, 2)
+	if sem := bh.getHostSemaphore(host); sem != nil {
+		sems = append(sems, sem)
+	}
+	if bh.global != nil {
+		sems = append(sems, bh.global)
+	}
+
+	for i, sem := range sems {
+		if err := bh.wait(ctx, sem, host); err != nil {
+			for _, taken := range sems[:i] {
+				taken.release()
+			}
+			return nil, err
+		}
+	}
+	bh.inFlight.Add(1)
+
+	once := sync.Once{}
+	return func() {
+		once.Do(func() {
+			bh.inFlight.Add(-1)
+			for _, sem := range sems {
+				sem.release()
+			}
+		})
+	}, nil
+}
+
+// wait takes a slot of the semaphore, it waits in the queue until a slot is available or the
+// context is done.
+func (bh *bulkhead) wait(ctx context.Context, sem *semaphore, host string) error {
+	select {
+	case sem.slots <- struct{}{}:
+		return nil
+	default:
+	}
+
+	if sem.maxQueue < 0 {
+		bh.rejected.Add(1)
+		return fmt.Errorf("%w: no available slot for %s", ErrBulkheadFull, host)
+	}
+	if waiting := sem.waiting.Add(1); sem.maxQueue > 0 && waiting > int64(sem.maxQueue) {
+		sem.waiting.Add(-1)
+		bh.rejected.Add(1)
+		return fmt.Errorf("%w: the queue is full for %s", ErrBulkheadFull, host)
+	}
+	defer sem.waiting.Add(-1)
+
+	bh.waiting.Add(1)
+	defer bh.waiting.Add(-1)
+
+	select {
+	case sem.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// getHostSemaphore returns the semaphore of the host, and it returns nil if no per-host limit is
+// set.
+func (bh *bulkhead) getHostSemaphore(host string) *semaphore {
+	if bh.config.MaxConcurrentPerHost <= 0 {
+		return nil
+	}
+
+	bh.mutex.Lock()
+	defer bh.mutex.Unlock()
+
+	sem, ok := bh.hosts[host]
+	if !ok {
+		sem = newSemaphore(bh.config.MaxConcurrentPerHost, bh.config.MaxQueue)
+		bh.hosts[host] = sem
+	}
+
+	return sem
+}
+
+// release returns a slot to the semaphore.
+func (sem *semaphore) release() {
+	<-sem.slots
+}
@@ -0,0 +1,149 @@
+package request
+
+import (
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ghosind/go-assert"
+)
+
+func TestBulkhead(t *testing.T) {
+	a := assert.New(t)
+
+	cli := New(Config{
+		Bulkhead: &BulkheadConfig{MaxConcurrent: 1, MaxQueue: -1},
+	})
+
+	resp, err := cli.GET("http://127.0.0.1:8080/test")
+	a.NilNow(err)
+	a.EqualNow(cli.BulkheadStats().InFlight, int64(1))
+
+	_, err = cli.GET("http://localhost:8080/test")
+	a.NotNilNow(err)
+	a.TrueNow(errors.Is(err, ErrBulkheadFull))
+	a.EqualNow(cli.BulkheadStats().Rejected, int64(1))
+
+	// the slot is released when the body is closed
+	resp.Body.Close()
+	resp.Body.Close()
+	a.EqualNow(cli.BulkheadStats().InFlight, int64(0))
+
+	resp, err = cli.GET("http://localhost:8080/test")
+	a.NilNow(err)
+	resp.Body.Close()
+
+	// the slot is released if it fails to send the request
+	_, err = cli.GET("http://127.0.0.1:1/test")
+	a.NotNilNow(err)
+	a.EqualNow(cli.BulkheadStats().InFlight, int64(0))
+
+	a.EqualNow(New().BulkheadStats(), BulkheadStats{})
+	a.NilNow(newBulkhead(&BulkheadConfig{MaxQueue: 10}))
+}
+
+func TestBulkheadWithFailedResponse(t *testing.T) {
+	a := assert.New(t)
+
+	cli := New(Config{
+		Bulkhead: &BulkheadConfig{MaxConcurrent: 1, MaxQueue: -1},
+	})
+	url := "http://127.0.0.1:8080/status?status=500"
+
+	// the slot is released when the wrappers get the failed response
+	_, _, err := ToObject[testResponse](cli.GET(url))
+	a.NotNilNow(err)
+	a.EqualNow(cli.BulkheadStats().InFlight, int64(0))
+
+	resp, err := cli.GET(url)
+	_, err = ToStream(resp, err, func(*testResponse) error { return nil })
+	a.NotNilNow(err)
+	resp, err = cli.GET(url)
+	_, err = ToNDJSON(resp, err, func(*testResponse) error { return nil })
+	a.NotNilNow(err)
+	resp, err = cli.GET(url)
+	_, _, err = ToWriter(resp, err, io.Discard)
+	a.NotNilNow(err)
+	resp, err = cli.GET(url)
+	_, _, err = ToFile(resp, err, filepath.Join(t.TempDir(), "file"))
+	a.NotNilNow(err)
+	_, _, err = ToString(cli.GET(url))
+	a.NotNilNow(err)
+	a.EqualNow(cli.BulkheadStats().InFlight, int64(0))
+
+	_, _, err = ToString(cli.GET("http://127.0.0.1:8080/test"))
+	a.NilNow(err)
+}
+
+func TestBulkheadQueue(t *testing.T) {
+	a := assert.New(t)
+
+	cli := New(Config{
+		Bulkhead: &BulkheadConfig{MaxConcurrentPerHost: 1, MaxQueue: 1},
+	})
+
+	resp, err := cli.GET("http://127.0.0.1:8080/test")
+	a.NilNow(err)
+
+	done := make(chan error)
+	go func() {
+		resp, err := cli.GET("http://127.0.0.1:8080/test")
+		if err == nil {
+			resp.Body.Close()
+		}
+		done <- err
+	}()
+
+	for i := 0; i < 100 && cli.BulkheadStats().Waiting == 0; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	a.EqualNow(cli.BulkheadStats().Waiting, int64(1))
+
+	// the queue is full
+	_, err = cli.GET("http://127.0.0.1:8080/test")
+	a.NotNilNow(err)
+	a.TrueNow(errors.Is(err, ErrBulkheadFull))
+
+	// the other host has its own slots
+	other, err := cli.GET("http://localhost:8080/test")
+	a.NilNow(err)
+	other.Body.Close()
+
+	resp.Body.Close()
+	a.NilNow(<-done)
+
+	stats := cli.BulkheadStats()
+	a.EqualNow(stats.InFlight, int64(0))
+	a.EqualNow(stats.Waiting, int64(0))
+	a.EqualNow(stats.Rejected, int64(1))
+}
+
+func TestBulkheadWaitingTimeout(t *testing.T) {
+	a := assert.New(t)
+
+	cli := New(Config{
+		Bulkhead: &BulkheadConfig{MaxConcurrent: 1, MaxConcurrentPerHost: 1},
+	})
+
+	resp, err := cli.GET("http://127.0.0.1:8080/test")
+	a.NilNow(err)
+	defer resp.Body.Close()
+
+	start := time.Now()
+	_, err = cli.GET("http://127.0.0.1:8080/test", RequestOptions{Timeout: 50})
+	a.NotNilNow(err)
+	a.TrueNow(IsTimeout(err))
+	a.TrueNow(time.Since(start) >= 50*time.Millisecond)
+
+	// the host's slot is returned if it fails to get the global slot
+	_, err = cli.GET("http://localhost:8080/test", RequestOptions{Timeout: 50})
+	a.TrueNow(IsTimeout(err))
+	a.EqualNow(len(cli.bulkhead.hosts["localhost:8080"].slots), 0)
+
+	stats := cli.BulkheadStats()
+	a.EqualNow(stats.InFlight, int64(1))
+	a.EqualNow(stats.Waiting, int64(0))
+	a.EqualNow(stats.Rejected, int64(0))
+}
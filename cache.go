@@ -0,0 +1,643 @@
+package request
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheStore is the storage of the cached responses. The client caches the responses of the GET
+// requests by the HTTP caching semantics (RFC 9111) if a cache store is set.
+type CacheStore interface {
+	// Get returns the cached entry by the key.
+	Get(key string) (*CacheEntry, bool)
+	// Set stores the entry with the key, and it overwrites the entry with the same key.
+	Set(key string, entry *CacheEntry)
+	// Delete removes the entry by the key.
+	Delete(key string)
+}
+
+// CacheEntry is a cached response.
+type CacheEntry struct {
+	// StatusCode is the status code of the response.
+	StatusCode int `json:"statusCode"`
+	// Header is the headers of the response.
+	Header http.Header `json:"header"`
+	// Body is the body of the response.
+	Body []byte `json:"body"`
+	// VaryHeader is the values of the request header fields that are listed in the `Vary` field of
+	// the response headers.
+	VaryHeader http.Header `json:"varyHeader,omitempty"`
+	// ResponseTime is the time that the response is received or revalidated.
+	ResponseTime time.Time `json:"responseTime"`
+}
+
+// cacheContextKey is the key of the cache status in the context of the cached responses' requests.
+type cacheContextKey struct{}
+
+// defaultHeuristicFreshness is the maximum freshness lifetime of the responses that have only the
+// `Last-Modified` field.
+const defaultHeuristicFreshness = 24 * time.Hour
+
+// defaultMaxCacheBodySize is the default maximum number of bytes of the response body that can be
+// cached, and the larger responses will not be cached.
+const defaultMaxCacheBodySize = 10 << 20
+
+// cacheableStatusCodes are the status codes that the responses are allowed to be cached.
+var cacheableStatusCodes = map[int]bool{
+	http.StatusOK:                   true,
+	http.StatusNonAuthoritativeInfo: true,
+	http.StatusNoContent:            true,
+	http.StatusMultipleChoices:      true,
+	http.StatusMovedPermanently:     true,
+	http.StatusNotFound:             true,
+	http.StatusMethodNotAllowed:     true,
+	http.StatusGone:                 true,
+	http.StatusRequestURITooLong:    true,
+	http.StatusNotImplemented:       true,
+}
+
+// IsFromCache returns true if the response is served from the cache of the client, including the
+// response that is revalidated by the server with the 304 (Not Modified) status code.
+func IsFromCache(resp *http.Response) bool {
+	if resp == nil || resp.Request == nil {
+		return false
+	}
+
+	fromCache, _ := resp.Request.Context().Value(cacheContextKey{}).(bool)
+	return fromCache
+}
+
+// sendRequestWithCache serves the request by the cache if there is a fresh cached response, or it
+// sends the request with the validators of the stale cached response. The responses will be
+// cached by the `Cache-Control` and the `Expires` fields of the response headers. The body is
+// copied into the cache while it's read, and the response will be stored after the whole body is
+// read if it's not larger than the max cache body size. The responses of the requests with the
+// `Authorization` field will not be stored unless they're explicitly allowed to be shared.
+func (cli *Client) sendRequestWithCache(
+	req *http.Request,
+	opt RequestOptions,
+) (*http.Response, error) {
+	store := cli.getCacheStore(opt)
+	if store == nil || parseCacheControl(req.Header).has("no-store") {
+		return cli.sendRequestWithHedging(req, opt)
+	}
+
+	key := getCacheKey(req)
+	if req.Method != http.MethodGet {
+		resp, err := cli.sendRequestWithHedging(req, opt)
+		if err == nil && req.Method != http.MethodHead && req.Method != http.MethodOptions &&
+			resp.StatusCode < http.StatusBadRequest {
+			store.Delete(key)
+		}
+		return resp, err
+	}
+
+	entry, ok := store.Get(key)
+	if ok && !entry.matchVary(req) {
+		entry, ok = nil, false
+	}
+
+	conditional := false
+	if ok {
+		if entry.isFresh(req, time.Now()) {
+			closeRequestBody(req.Body)
+			return entry.response(req), nil
+		}
+		conditional = entry.setValidators(req)
+	}
+
+	resp, err := cli.sendRequestWithHedging(req, opt)
+	if err != nil {
+		return resp, err
+	}
+
+	if conditional && resp.StatusCode == http.StatusNotModified {
+		discardResponse(resp)
+		entry.update(resp.Header)
+		store.Set(key, entry)
+		return entry.response(req), nil
+	}
+
+	limit := cli.getMaxCacheBodySize()
+	if !isResponseCacheable(resp) || resp.ContentLength > limit ||
+		(isAuthorizedRequest(req, resp) && !isResponseShareable(resp)) {
+		return resp, nil
+	}
+
+	entry = newCacheEntry(req, resp, nil)
+	resp.Body = &cachingBody{
+		ReadCloser: resp.Body,
+		size:       resp.ContentLength,
+		limit:      limit,
+		store: func(body []byte) {
+			entry.Body = body
+			store.Set(key, entry)
+		},
+	}
+
+	return resp, nil
+}
+
+// getMaxCacheBodySize returns the maximum number of bytes of the response body that can be cached.
+func (cli *Client) getMaxCacheBodySize() int64 {
+	if cli.MaxCacheBodySize > 0 {
+		return cli.MaxCacheBodySize
+	}
+
+	return defaultMaxCacheBodySize
+}
+
+// getCacheStore returns the cache store of the client, and it returns nil if the cache is
+// disabled for the request.
+func (cli *Client) getCacheStore(opt RequestOptions) CacheStore {
+	if opt.DisableCache {
+		return nil
+	}
+
+	return cli.Cache
+}
+
+// getCacheKey returns the key of the cached response for the request.
+func getCacheKey(req *http.Request) string {
+	return http.MethodGet + " " + req.URL.String()
+}
+
+// isResponseCacheable checks whether the response can be stored in the cache.
+func isResponseCacheable(resp *http.Response) bool {
+	if !cacheableStatusCodes[resp.StatusCode] || resp.Header.Get("Vary") == "*" {
+		return false
+	}
+
+	cc := parseCacheControl(resp.Header)
+	if cc.has("no-store") {
+		return false
+	}
+
+	hasValidator := resp.Header.Get("ETag") != "" || resp.Header.Get("Last-Modified") != ""
+	if cc.has("no-cache") {
+		// the response is never fresh, and it can be used only after revalidation.
+		return hasValidator
+	}
+
+	return hasValidator || cc.has("max-age") || resp.Header.Get("Expires") != ""
+}
+
+// isAuthorizedRequest checks whether the request is sent with the `Authorization` field, which
+// may be added by the authenticator or the signer while sending.
+func isAuthorizedRequest(req *http.Request, resp *http.Response) bool {
+	if req.Header.Get("Authorization") != "" {
+		return true
+	}
+
+	return resp.Request != nil && resp.Request.Header.Get("Authorization") != ""
+}
+
+// isResponseShareable checks whether the response of the request with the `Authorization` field
+// can be stored and reused for other requests (RFC 9111 Section 3.5).
+func isResponseShareable(resp *http.Response) bool {
+	cc := parseCacheControl(resp.Header)
+	return cc.has("public") || cc.has("s-maxage") || cc.has("must-revalidate")
+}
+
+// newCacheEntry creates an entry for the response.
+func newCacheEntry(req *http.Request, resp *http.Response, body []byte) *CacheEntry {
+	entry := &CacheEntry{
+		StatusCode:   resp.StatusCode,
+		Header:       resp.Header.Clone(),
+		Body:         body,
+		ResponseTime: time.Now(),
+	}
+
+	for _, field := range getVaryFields(resp.Header) {
+		if entry.VaryHeader == nil {
+			entry.VaryHeader = make(http.Header)
+		}
+		entry.VaryHeader[field] = req.Header.Values(field)
+	}
+
+	return entry
+}
+
+// getVaryFields returns the canonical names of the fields in the `Vary` field.
+func getVaryFields(header http.Header) []string {
+	fields := make([]string, 0)
+
+	for _, value := range header.Values("Vary") {
+		for _, field := range strings.Split(value, ",") {
+			field = strings.TrimSpace(field)
+			if field != "" {
+				fields = append(fields, http.CanonicalHeaderKey(field))
+			}
+		}
+	}
+
+	return fields
+}
+
+// matchVary checks whether the request has the same values of the fields in the `Vary` field as
+// the request of the cached response.
+func (entry *CacheEntry) matchVary(req *http.Request) bool {
+	for _, field := range getVaryFields(entry.Header) {
+		if strings.Join(req.Header.Values(field), ",") !=
+			strings.Join(entry.VaryHeader.Values(field), ",") {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isFresh checks whether the cached response can be used without revalidation.
+func (entry *CacheEntry) isFresh(req *http.Request, now time.Time) bool {
+	reqCC := parseCacheControl(req.Header)
+	if reqCC.has("no-cache") || strings.Contains(req.Header.Get("Pragma"), "no-cache") {
+		return false
+	}
+
+	lifetime := entry.freshnessLifetime()
+	if maxAge, ok := reqCC.duration("max-age"); ok && maxAge < lifetime {
+		lifetime = maxAge
+	}
+
+	return entry.age(now) < lifetime
+}
+
+// freshnessLifetime returns the duration that the cached response is fresh after it's generated.
+func (entry *CacheEntry) freshnessLifetime() time.Duration {
+	cc := parseCacheControl(entry.Header)
+	if cc.has("no-cache") {
+		return 0
+	} else if maxAge, ok := cc.duration("max-age"); ok {
+		return maxAge
+	}
+
+	date := entry.date()
+
+	if expiresValue := entry.Header.Get("Expires"); expiresValue != "" {
+		expires, err := http.ParseTime(expiresValue)
+		if err != nil {
+			return 0
+		}
+		return expires.Sub(date)
+	}
+
+	if lastModified, err := http.ParseTime(entry.Header.Get("Last-Modified")); err == nil {
+		lifetime := date.Sub(lastModified) / 10
+		if lifetime > defaultHeuristicFreshness {
+			lifetime = defaultHeuristicFreshness
+		}
+		return lifetime
+	}
+
+	return 0
+}
+
+// age returns the age of the cached response.
+func (entry *CacheEntry) age(now time.Time) time.Duration {
+	age := now.Sub(entry.ResponseTime)
+
+	ageValue, err := strconv.ParseInt(entry.Header.Get("Age"), 10, 64)
+	if err == nil && ageValue > 0 {
+		age += time.Duration(ageValue) * time.Second
+	}
+
+	return age
+}
+
+// date returns the value of the `Date` field of the cached response, and it returns the response
+// time if the field is invalid.
+func (entry *CacheEntry) date() time.Time {
+	if date, err := http.ParseTime(entry.Header.Get("Date")); err == nil {
+		return date
+	}
+
+	return entry.ResponseTime
+}
+
+// setValidators adds the validators of the cached response to the request, and it returns false if
+// the cached response has no validators or the request has its own validators.
+func (entry *CacheEntry) setValidators(req *http.Request) bool {
+	if req.Header.Get("If-None-Match") != "" || req.Header.Get("If-Modified-Since") != "" {
+		return false
+	}
+
+	etag := entry.Header.Get("ETag")
+	lastModified := entry.Header.Get("Last-Modified")
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	if lastModified != "" {
+		req.Header.Set("If-Modified-Since", lastModified)
+	}
+
+	return etag != "" || lastModified != ""
+}
+
+// update updates the cached response by the headers of the 304 (Not Modified) response.
+func (entry *CacheEntry) update(header http.Header) {
+	for field, values := range header {
+		switch field {
+		case "Content-Length", "Content-Encoding", "Transfer-Encoding":
+			continue
+		}
+		entry.Header[field] = values
+	}
+	entry.Header.Del("Age")
+	entry.ResponseTime = time.Now()
+}
+
+// response creates a response from the cached entry for the request.
+func (entry *CacheEntry) response(req *http.Request) *http.Response {
+	header := entry.Header.Clone()
+	header.Set("Age", strconv.FormatInt(int64(entry.age(time.Now())/time.Second), 10))
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", entry.StatusCode, http.StatusText(entry.StatusCode)),
+		StatusCode:    entry.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(entry.Body)),
+		ContentLength: int64(len(entry.Body)),
+		Request:       req.WithContext(context.WithValue(req.Context(), cacheContextKey{}, true)),
+	}
+}
+
+// cacheControl is the directives of the `Cache-Control` field.
+type cacheControl map[string]string
+
+// parseCacheControl parses the directives of the `Cache-Control` field.
+func parseCacheControl(header http.Header) cacheControl {
+	cc := make(cacheControl)
+
+	for _, value := range header.Values("Cache-Control") {
+		for _, directive := range strings.Split(value, ",") {
+			name, arg, _ := strings.Cut(strings.TrimSpace(directive), "=")
+			if name != "" {
+				cc[strings.ToLower(name)] = strings.Trim(arg, `"`)
+			}
+		}
+	}
+
+	return cc
+}
+
+// has checks whether the directive exists.
+func (cc cacheControl) has(name string) bool {
+	_, ok := cc[name]
+	return ok
+}
+
+// duration returns the value of the directive as the number of seconds.
+func (cc cacheControl) duration(name string) (time.Duration, bool) {
+	value, ok := cc[name]
+	if !ok {
+		return 0, false
+	}
+
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || seconds < 0 {
+		return 0, true
+	}
+
+	return time.Duration(seconds) * time.Second, true
+}
+
+// MemoryCacheStore is an in-memory cache store that evicts the least recently used entries if
+// the number of the entries exceeds the capacity.
+type MemoryCacheStore struct {
+	// capacity is the maximum number of the entries.
+	capacity int
+	// entries are the elements of the entries by the keys.
+	entries map[string]*list.Element
+	// lru is the list of the entries, the front element is the most recently used one.
+	lru *list.List
+	// mutex is the locker for the store.
+	mutex sync.Mutex
+}
+
+// memoryCacheItem is the element of the memory cache store.
+type memoryCacheItem struct {
+	key   string
+	entry *CacheEntry
+}
+
+// defaultMemoryCacheCapacity is the default capacity of the memory cache store.
+const defaultMemoryCacheCapacity = 1000
+
+// NewMemoryCacheStore creates an in-memory cache store with the capacity, and the capacity is
+// 1000 entries if it's less than or equal to 0.
+//
+//	cli := request.New(request.Config{
+//	  Cache: request.NewMemoryCacheStore(100),
+//	})
+func NewMemoryCacheStore(capacity int) *MemoryCacheStore {
+	if capacity <= 0 {
+		capacity = defaultMemoryCacheCapacity
+	}
+
+	return &MemoryCacheStore{
+		capacity: capacity,
+		entries:  make(map[string]*list.Element),
+		lru:      list.New(),
+	}
+}
+
+// Get returns the cached entry by the key, and marks it as the most recently used one.
+func (store *MemoryCacheStore) Get(key string) (*CacheEntry, bool) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	elem, ok := store.entries[key]
+	if !ok {
+		return nil, false
+	}
+	store.lru.MoveToFront(elem)
+
+	return elem.Value.(*memoryCacheItem).entry.clone(), true
+}
+
+// Set stores the entry with the key, and it evicts the least recently used entry if the store is
+// full.
+func (store *MemoryCacheStore) Set(key string, entry *CacheEntry) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if elem, ok := store.entries[key]; ok {
+		elem.Value.(*memoryCacheItem).entry = entry.clone()
+		store.lru.MoveToFront(elem)
+		return
+	}
+
+	store.entries[key] = store.lru.PushFront(&memoryCacheItem{key: key, entry: entry.clone()})
+
+	for store.lru.Len() > store.capacity {
+		elem := store.lru.Back()
+		store.lru.Remove(elem)
+		delete(store.entries, elem.Value.(*memoryCacheItem).key)
+	}
+}
+
+// Delete removes the entry by the key.
+func (store *MemoryCacheStore) Delete(key string) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if elem, ok := store.entries[key]; ok {
+		store.lru.Remove(elem)
+		delete(store.entries, key)
+	}
+}
+
+// Len returns the number of the entries in the store.
+func (store *MemoryCacheStore) Len() int {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	return store.lru.Len()
+}
+
+// clone returns a copy of the entry, so the modification of the entry will not affect the stored
+// one.
+func (entry *CacheEntry) clone() *CacheEntry {
+	cloned := *entry
+	cloned.Header = entry.Header.Clone()
+	cloned.VaryHeader = entry.VaryHeader.Clone()
+
+	return &cloned
+}
+
+// DiskCacheStore is a cache store that saves the entries as files in a directory, to keep the
+// cached responses between process runs. The errors of the file operations are ignored, and the
+// request will be sent to the server if it fails to read the cached response.
+type DiskCacheStore struct {
+	// dir is the directory of the cache files.
+	dir string
+}
+
+// NewDiskCacheStore creates a cache store in the directory, and the directory will be created if
+// it doesn't exist.
+//
+//	store, err := request.NewDiskCacheStore(filepath.Join(os.TempDir(), "http-cache"))
+//	if err != nil {
+//	  // Error handling
+//	}
+//	cli := request.New(request.Config{
+//	  Cache: store,
+//	})
+func NewDiskCacheStore(dir string) (*DiskCacheStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	return &DiskCacheStore{dir: dir}, nil
+}
+
+// Get reads the cached entry of the key from the file.
+func (store *DiskCacheStore) Get(key string) (*CacheEntry, bool) {
+	data, err := os.ReadFile(store.path(key))
+	if err != nil {
+		return nil, false
+	}
+
+	entry := new(CacheEntry)
+	if err := json.Unmarshal(data, entry); err != nil {
+		return nil, false
+	}
+
+	return entry, true
+}
+
+// Set writes the entry into the file of the key.
+func (store *DiskCacheStore) Set(key string, entry *CacheEntry) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	file, err := os.CreateTemp(store.dir, "*.tmp")
+	if err != nil {
+		return
+	}
+	defer os.Remove(file.Name())
+
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err != nil || closeErr != nil {
+		return
+	}
+
+	os.Rename(file.Name(), store.path(key))
+}
+
+// Delete removes the file of the key.
+func (store *DiskCacheStore) Delete(key string) {
+	os.Remove(store.path(key))
+}
+
+// path returns the path of the cache file for the key.
+func (store *DiskCacheStore) path(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return filepath.Join(store.dir, hex.EncodeToString(hash[:])+".json")
+}
+
+// cachingBody is the response body that copies the data into a buffer while it's read, and it
+// stores the buffered body into the cache after the whole body is read. It stops buffering if the
+// body is larger than the limitation, and the response will not be cached if the body is not read
+// completely.
+type cachingBody struct {
+	io.ReadCloser
+	buffer bytes.Buffer
+	// size is the number of bytes of the body, and it's -1 if the size is unknown.
+	size  int64
+	limit int64
+	store func(body []byte)
+	// done indicates whether the body has been stored or it's skipped.
+	done bool
+}
+
+// Read reads the data from the underlying body, and copies it into the buffer.
+func (body *cachingBody) Read(p []byte) (int, error) {
+	n, err := body.ReadCloser.Read(p)
+	if body.done {
+		return n, err
+	}
+
+	if int64(body.buffer.Len()+n) > body.limit {
+		body.skip()
+		return n, err
+	}
+	body.buffer.Write(p[:n])
+
+	if err == io.EOF || int64(body.buffer.Len()) == body.size {
+		body.done = true
+		body.store(body.buffer.Bytes())
+	}
+
+	return n, err
+}
+
+// Close closes the underlying body and releases the buffer.
+func (body *cachingBody) Close() error {
+	body.skip()
+	return body.ReadCloser.Close()
+}
+
+// skip stops buffering the body, and the response will not be cached.
+func (body *cachingBody) skip() {
+	body.done = true
+	body.buffer = bytes.Buffer{}
+}
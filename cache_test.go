@@ -0,0 +1,298 @@
+package request
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ghosind/go-assert"
+)
+
+const cacheURL = "http://127.0.0.1:8080/cache"
+
+func getCacheAttempt(a *assert.Assertion, cli *Client, url string, opt ...RequestOptions) (
+	string,
+	bool,
+) {
+	data, resp, err := ToObject[testResponse](cli.GET(url, opt...))
+	a.NilNow(err)
+	a.EqualNow(resp.StatusCode, http.StatusOK)
+	a.EqualNow(*data.Path, "/cache")
+
+	return resp.Header.Get("X-Attempt"), IsFromCache(resp)
+}
+
+func TestCacheFreshResponse(t *testing.T) {
+	a := assert.New(t)
+	cli := New(Config{Cache: NewMemoryCacheStore(0)})
+	url := cacheURL + "?key=fresh&cacheControl=max-age%3D60"
+
+	attempt, fromCache := getCacheAttempt(a, cli, url)
+	a.EqualNow(attempt, "1")
+	a.NotTrueNow(fromCache)
+
+	attempt, fromCache = getCacheAttempt(a, cli, url)
+	a.EqualNow(attempt, "1")
+	a.TrueNow(fromCache)
+
+	// bypass the cache
+	attempt, fromCache = getCacheAttempt(a, cli, url, RequestOptions{DisableCache: true})
+	a.EqualNow(attempt, "2")
+	a.NotTrueNow(fromCache)
+
+	// revalidate by the request
+	attempt, fromCache = getCacheAttempt(a, cli, url, RequestOptions{
+		Headers: map[string][]string{"Cache-Control": {"no-cache"}},
+	})
+	a.EqualNow(attempt, "3")
+	a.NotTrueNow(fromCache)
+
+	// not cached by other clients
+	attempt, _ = getCacheAttempt(a, New(), url)
+	a.EqualNow(attempt, "4")
+}
+
+func TestCacheRevalidation(t *testing.T) {
+	a := assert.New(t)
+	cli := New(Config{Cache: NewMemoryCacheStore(0)})
+
+	url := cacheURL + "?key=etag&cacheControl=no-cache&etag=%22v1%22"
+	attempt, fromCache := getCacheAttempt(a, cli, url)
+	a.EqualNow(attempt, "1")
+	a.NotTrueNow(fromCache)
+
+	attempt, fromCache = getCacheAttempt(a, cli, url)
+	a.EqualNow(attempt, "2")
+	a.TrueNow(fromCache)
+
+	url = cacheURL + "?key=last-modified&cacheControl=max-age%3D0&lastModified=1"
+	attempt, fromCache = getCacheAttempt(a, cli, url)
+	a.EqualNow(attempt, "1")
+	a.NotTrueNow(fromCache)
+
+	attempt, fromCache = getCacheAttempt(a, cli, url)
+	a.EqualNow(attempt, "2")
+	a.TrueNow(fromCache)
+
+	// with the validators of the request
+	resp, err := cli.GET(url, RequestOptions{
+		Headers: map[string][]string{"If-Modified-Since": {"Mon, 01 Jan 2024 00:00:00 GMT"}},
+	})
+	a.NilNow(err)
+	a.NotTrueNow(IsFromCache(resp))
+	a.EqualNow(resp.Header.Get("X-Attempt"), "3")
+	resp.Body.Close()
+}
+
+func TestCacheNotCacheable(t *testing.T) {
+	a := assert.New(t)
+	cli := New(Config{Cache: NewMemoryCacheStore(0)})
+
+	for _, url := range []string{
+		cacheURL + "?key=no-store&cacheControl=no-store",
+		cacheURL + "?key=no-validators",
+		cacheURL + "?key=no-cache-no-validators&cacheControl=no-cache",
+		cacheURL + "?key=vary-all&cacheControl=max-age%3D60&vary=*",
+	} {
+		attempt, _ := getCacheAttempt(a, cli, url)
+		a.EqualNow(attempt, "1")
+		attempt, fromCache := getCacheAttempt(a, cli, url)
+		a.EqualNow(attempt, "2")
+		a.NotTrueNow(fromCache)
+	}
+
+	// the request forbids storing the response
+	url := cacheURL + "?key=request-no-store&cacheControl=max-age%3D60"
+	for i := 0; i < 2; i++ {
+		_, fromCache := getCacheAttempt(a, cli, url, RequestOptions{
+			Headers: map[string][]string{"Cache-Control": {"no-store"}},
+		})
+		a.NotTrueNow(fromCache)
+	}
+}
+
+func TestCacheBodySize(t *testing.T) {
+	a := assert.New(t)
+	cli := New(Config{Cache: NewMemoryCacheStore(0)})
+	url := cacheURL + "?key=body-size&cacheControl=max-age%3D60"
+
+	// the body is not read
+	resp, err := cli.GET(url)
+	a.NilNow(err)
+	a.EqualNow(resp.Header.Get("X-Attempt"), "1")
+	resp.Body.Close()
+
+	attempt, fromCache := getCacheAttempt(a, cli, url)
+	a.EqualNow(attempt, "2")
+	a.NotTrueNow(fromCache)
+
+	attempt, fromCache = getCacheAttempt(a, cli, url)
+	a.EqualNow(attempt, "2")
+	a.TrueNow(fromCache)
+
+	// the body is larger than the cache limitation
+	cli = New(Config{Cache: NewMemoryCacheStore(0), MaxCacheBodySize: 10})
+	url = cacheURL + "?key=cache-body-size&cacheControl=max-age%3D60"
+	for i := 1; i <= 2; i++ {
+		attempt, fromCache = getCacheAttempt(a, cli, url)
+		a.EqualNow(attempt, strconv.Itoa(i))
+		a.NotTrueNow(fromCache)
+	}
+}
+
+func TestCacheAuthorizedRequest(t *testing.T) {
+	a := assert.New(t)
+	cli := New(Config{Cache: NewMemoryCacheStore(0)})
+	withToken := func(token string) RequestOptions {
+		return RequestOptions{Authenticator: &BearerAuth{Token: token}}
+	}
+
+	url := cacheURL + "?key=authorized&cacheControl=max-age%3D60"
+	attempt, fromCache := getCacheAttempt(a, cli, url, withToken("token-a"))
+	a.EqualNow(attempt, "1")
+	a.NotTrueNow(fromCache)
+
+	// not served to other credentials
+	attempt, fromCache = getCacheAttempt(a, cli, url, withToken("token-b"))
+	a.EqualNow(attempt, "2")
+	a.NotTrueNow(fromCache)
+
+	// the response is allowed to be shared
+	url = cacheURL + "?key=authorized-public&cacheControl=public%2C%20max-age%3D60"
+	attempt, fromCache = getCacheAttempt(a, cli, url, withToken("token-a"))
+	a.EqualNow(attempt, "1")
+	a.NotTrueNow(fromCache)
+
+	attempt, fromCache = getCacheAttempt(a, cli, url, withToken("token-b"))
+	a.EqualNow(attempt, "1")
+	a.TrueNow(fromCache)
+}
+
+func TestCacheVary(t *testing.T) {
+	a := assert.New(t)
+	cli := New(Config{Cache: NewMemoryCacheStore(0)})
+	url := cacheURL + "?key=vary&cacheControl=max-age%3D60&vary=X-Lang"
+
+	withLang := func(lang string) RequestOptions {
+		return RequestOptions{Headers: map[string][]string{"X-Lang": {lang}}}
+	}
+
+	attempt, _ := getCacheAttempt(a, cli, url, withLang("en"))
+	a.EqualNow(attempt, "1")
+	attempt, _ = getCacheAttempt(a, cli, url, withLang("en"))
+	a.EqualNow(attempt, "1")
+	attempt, _ = getCacheAttempt(a, cli, url, withLang("zh"))
+	a.EqualNow(attempt, "2")
+}
+
+func TestCacheInvalidation(t *testing.T) {
+	a := assert.New(t)
+	cli := New(Config{Cache: NewMemoryCacheStore(0)})
+	url := cacheURL + "?key=invalidation&cacheControl=max-age%3D60"
+
+	attempt, _ := getCacheAttempt(a, cli, url)
+	a.EqualNow(attempt, "1")
+
+	_, err := cli.HEAD(url)
+	a.NilNow(err)
+	attempt, fromCache := getCacheAttempt(a, cli, url)
+	a.EqualNow(attempt, "1")
+	a.TrueNow(fromCache)
+
+	_, err = cli.POST(url, RequestOptions{Body: "data"})
+	a.NilNow(err)
+	attempt, fromCache = getCacheAttempt(a, cli, url)
+	a.EqualNow(attempt, "4")
+	a.NotTrueNow(fromCache)
+}
+
+func TestMemoryCacheStore(t *testing.T) {
+	a := assert.New(t)
+	store := NewMemoryCacheStore(2)
+
+	store.Set("a", &CacheEntry{StatusCode: http.StatusOK, Header: http.Header{}})
+	store.Set("b", &CacheEntry{StatusCode: http.StatusOK, Header: http.Header{}})
+	_, ok := store.Get("a")
+	a.TrueNow(ok)
+
+	store.Set("c", &CacheEntry{StatusCode: http.StatusOK, Header: http.Header{}})
+	a.EqualNow(store.Len(), 2)
+	_, ok = store.Get("b")
+	a.NotTrueNow(ok)
+
+	store.Set("a", &CacheEntry{StatusCode: http.StatusNotFound, Header: http.Header{}})
+	entry, ok := store.Get("a")
+	a.TrueNow(ok)
+	a.EqualNow(entry.StatusCode, http.StatusNotFound)
+
+	// modifying the returned entry does not affect the stored one
+	entry.Header.Set("X-Test", "test")
+	entry, _ = store.Get("a")
+	a.EqualNow(entry.Header.Get("X-Test"), "")
+
+	store.Delete("a")
+	store.Delete("not-exists")
+	a.EqualNow(store.Len(), 1)
+}
+
+func TestDiskCacheStore(t *testing.T) {
+	a := assert.New(t)
+	dir := t.TempDir()
+	url := cacheURL + "?key=disk&cacheControl=max-age%3D60"
+
+	store, err := NewDiskCacheStore(dir)
+	a.NilNow(err)
+	attempt, _ := getCacheAttempt(a, New(Config{Cache: store}), url)
+	a.EqualNow(attempt, "1")
+
+	store, err = NewDiskCacheStore(dir)
+	a.NilNow(err)
+	attempt, fromCache := getCacheAttempt(a, New(Config{Cache: store}), url)
+	a.EqualNow(attempt, "1")
+	a.TrueNow(fromCache)
+
+	store.Delete(getCacheKey(&http.Request{Method: http.MethodGet, URL: mustParseURL(url)}))
+	_, ok := store.Get(getCacheKey(&http.Request{Method: http.MethodGet, URL: mustParseURL(url)}))
+	a.NotTrueNow(ok)
+}
+
+func TestCacheFreshnessLifetime(t *testing.T) {
+	a := assert.New(t)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	newEntry := func(header http.Header) *CacheEntry {
+		header.Set("Date", now.Format(http.TimeFormat))
+		return &CacheEntry{Header: header, ResponseTime: now}
+	}
+
+	a.EqualNow(newEntry(http.Header{
+		"Cache-Control": {"public, max-age=30"},
+		"Expires":       {now.Add(time.Hour).Format(http.TimeFormat)},
+	}).freshnessLifetime(), 30*time.Second)
+	a.EqualNow(newEntry(http.Header{
+		"Expires": {now.Add(time.Hour).Format(http.TimeFormat)},
+	}).freshnessLifetime(), time.Hour)
+	a.EqualNow(newEntry(http.Header{"Expires": {"0"}}).freshnessLifetime(), time.Duration(0))
+	a.EqualNow(newEntry(http.Header{
+		"Last-Modified": {now.Add(-10 * time.Hour).Format(http.TimeFormat)},
+	}).freshnessLifetime(), time.Hour)
+	a.EqualNow(newEntry(http.Header{
+		"Last-Modified": {now.Add(-1000 * time.Hour).Format(http.TimeFormat)},
+	}).freshnessLifetime(), defaultHeuristicFreshness)
+	a.EqualNow(newEntry(http.Header{
+		"Cache-Control": {"max-age=60"},
+	}).age(now.Add(10*time.Second)), 10*time.Second)
+	a.EqualNow(newEntry(http.Header{
+		"Age": {"5"},
+	}).age(now.Add(10*time.Second)), 15*time.Second)
+}
+
+func mustParseURL(rawURL string) *url.URL {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		panic(err)
+	}
+	return u
+}
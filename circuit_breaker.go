@@ -0,0 +1,314 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// CircuitState is the state of a circuit breaker.
+type CircuitState int
+
+const (
+	// CircuitClosed indicates the requests are allowed, and the circuit will be opened if the
+	// number of the consecutive failures reaches the threshold.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen indicates the requests will fail with an `ErrCircuitOpen` error without sending,
+	// and the circuit will be half-open after the open timeout.
+	CircuitOpen
+	// CircuitHalfOpen indicates a limited number of trial requests are allowed, and the circuit
+	// will be closed if all of them succeed, or be opened again if any of them fails.
+	CircuitHalfOpen
+)
+
+// String returns the name of the state.
+func (state CircuitState) String() string {
+	switch state {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(state))
+	}
+}
+
+// CircuitBreakerConfig defines the settings of the circuit breakers of a client. The client has a
+// circuit breaker for each key, and the key is the host of the request by default.
+//
+//	cli := request.New(request.Config{
+//	  CircuitBreaker: &request.CircuitBreakerConfig{
+//	    FailureThreshold: 5,
+//	    OpenTimeout:      30 * time.Second,
+//	    OnStateChange: func(key string, from, to request.CircuitState) {
+//	      log.Printf("circuit %s changed from %s to %s", key, from, to)
+//	    },
+//	  },
+//	})
+type CircuitBreakerConfig struct {
+	// FailureThreshold is the number of the consecutive failures to open the circuit, default 5.
+	FailureThreshold int
+	// OpenTimeout is the duration that the circuit keeps open before it becomes half-open, default
+	// 30 seconds.
+	OpenTimeout time.Duration
+	// HalfOpenMaxRequests is the number of the trial requests that are allowed when the circuit is
+	// half-open, and the circuit will be closed if all of them succeed, default 1.
+	HalfOpenMaxRequests int
+	// KeyFunc returns the key of the circuit breaker for the request, default the host of the
+	// request.
+	KeyFunc func(req *http.Request) string
+	// IsFailure decides whether the attempt is failed by the response and the error. By default,
+	// the attempt is failed if it fails to send the request, or the status code of the response is
+	// invalid by the `ValidateStatus` function of the request or the client. The requests that are
+	// canceled by the context will not be counted.
+	IsFailure func(resp *http.Response, err error) bool
+	// OnStateChange is called when the state of the circuit breaker changes, with the key of the
+	// circuit breaker, the previous state, and the new state.
+	OnStateChange func(key string, from, to CircuitState)
+}
+
+// circuitBreaker manages the circuits of a client by the keys.
+type circuitBreaker struct {
+	// config is the settings of the circuit breaker.
+	config CircuitBreakerConfig
+	// circuits are the circuits by the keys.
+	circuits map[string]*circuit
+	// changes are the state changes that are not notified yet.
+	changes []circuitStateChange
+	// mutex is the locker for the circuits and the changes.
+	mutex sync.Mutex
+}
+
+// circuitStateChange is a state change of a circuit.
+type circuitStateChange struct {
+	key  string
+	from CircuitState
+	to   CircuitState
+}
+
+// circuit is the state of the circuit breaker for a key.
+type circuit struct {
+	state CircuitState
+	// failures is the number of the consecutive failures in the closed state.
+	failures int
+	// trials is the number of the trial requests in the half-open state.
+	trials int
+	// successes is the number of the succeeded trial requests in the half-open state.
+	successes int
+	// openedAt is the time that the circuit is opened.
+	openedAt time.Time
+	// generation increases when the state changes, and the results of the requests from a
+	// previous generation will be ignored.
+	generation uint64
+}
+
+const (
+	// defaultCircuitFailureThreshold is the default number of the failures to open the circuit.
+	defaultCircuitFailureThreshold = 5
+	// defaultCircuitOpenTimeout is the default duration that the circuit keeps open.
+	defaultCircuitOpenTimeout = 30 * time.Second
+)
+
+// newCircuitBreaker creates a circuit breaker by the config, and it returns nil if the config is
+// nil.
+func newCircuitBreaker(config *CircuitBreakerConfig) *circuitBreaker {
+	if config == nil {
+		return nil
+	}
+
+	breaker := &circuitBreaker{
+		config:   *config,
+		circuits: make(map[string]*circuit),
+	}
+	if breaker.config.FailureThreshold <= 0 {
+		breaker.config.FailureThreshold = defaultCircuitFailureThreshold
+	}
+	if breaker.config.OpenTimeout <= 0 {
+		breaker.config.OpenTimeout = defaultCircuitOpenTimeout
+	}
+	if breaker.config.HalfOpenMaxRequests <= 0 {
+		breaker.config.HalfOpenMaxRequests = 1
+	}
+
+	return breaker
+}
+
+// CircuitState returns the state of the circuit breaker with the key, and it returns
+// `CircuitClosed` if no circuit breaker is set or no request has been sent with the key.
+//
+//	state := cli.CircuitState("api.example.com")
+func (cli *Client) CircuitState(key string) CircuitState {
+	breaker := cli.circuitBreaker
+	if breaker == nil {
+		return CircuitClosed
+	}
+
+	breaker.mutex.Lock()
+	defer breaker.unlock()
+
+	c, ok := breaker.circuits[key]
+	if !ok {
+		return CircuitClosed
+	}
+
+	breaker.refresh(key, c, time.Now())
+	return c.state
+}
+
+// doRequestWithCircuitBreaker sends the request by the HTTP client through the circuit breaker of
+// the client. It returns an `ErrCircuitOpen` error without sending the request if the circuit is
+// open.
+func (cli *Client) doRequestWithCircuitBreaker(
+	httpClient *http.Client,
+	req *http.Request,
+	opt RequestOptions,
+) (*http.Response, error) {
+	breaker := cli.circuitBreaker
+	if breaker == nil {
+		return httpClient.Do(req)
+	}
+
+	key := breaker.key(req)
+	generation, err := breaker.allow(key)
+	if err != nil {
+		closeRequestBody(req.Body)
+		return nil, err
+	}
+
+	resp, err := httpClient.Do(req)
+	if errors.Is(err, context.Canceled) {
+		breaker.release(key, generation)
+	} else {
+		breaker.record(key, generation, breaker.isFailure(resp, err, cli.getValidateStatus(opt)))
+	}
+
+	return resp, err
+}
+
+// key returns the key of the circuit for the request.
+func (breaker *circuitBreaker) key(req *http.Request) string {
+	if breaker.config.KeyFunc != nil {
+		return breaker.config.KeyFunc(req)
+	}
+
+	return req.URL.Host
+}
+
+// isFailure checks whether the attempt is failed.
+func (breaker *circuitBreaker) isFailure(
+	resp *http.Response,
+	err error,
+	validateStatus func(int) bool,
+) bool {
+	if breaker.config.IsFailure != nil {
+		return breaker.config.IsFailure(resp, err)
+	}
+
+	return err != nil || !validateStatus(resp.StatusCode)
+}
+
+// allow checks whether the request can be sent, and it returns the generation of the circuit.
+func (breaker *circuitBreaker) allow(key string) (uint64, error) {
+	breaker.mutex.Lock()
+	defer breaker.unlock()
+
+	c, ok := breaker.circuits[key]
+	if !ok {
+		c = &circuit{}
+		breaker.circuits[key] = c
+	}
+	breaker.refresh(key, c, time.Now())
+
+	switch c.state {
+	case CircuitOpen:
+		return 0, fmt.Errorf("%w: %s", ErrCircuitOpen, key)
+	case CircuitHalfOpen:
+		if c.trials >= breaker.config.HalfOpenMaxRequests {
+			return 0, fmt.Errorf("%w: %s", ErrCircuitOpen, key)
+		}
+		c.trials++
+	}
+
+	return c.generation, nil
+}
+
+// record updates the circuit by the result of the request.
+func (breaker *circuitBreaker) record(key string, generation uint64, failed bool) {
+	breaker.mutex.Lock()
+	defer breaker.unlock()
+
+	c := breaker.circuits[key]
+	if c == nil || c.generation != generation {
+		return
+	}
+
+	switch c.state {
+	case CircuitClosed:
+		if !failed {
+			c.failures = 0
+		} else if c.failures++; c.failures >= breaker.config.FailureThreshold {
+			breaker.setState(key, c, CircuitOpen, time.Now())
+		}
+	case CircuitHalfOpen:
+		if failed {
+			breaker.setState(key, c, CircuitOpen, time.Now())
+		} else if c.successes++; c.successes >= breaker.config.HalfOpenMaxRequests {
+			breaker.setState(key, c, CircuitClosed, time.Now())
+		}
+	}
+}
+
+// release returns the trial of the request that is not counted.
+func (breaker *circuitBreaker) release(key string, generation uint64) {
+	breaker.mutex.Lock()
+	defer breaker.unlock()
+
+	c := breaker.circuits[key]
+	if c != nil && c.generation == generation && c.state == CircuitHalfOpen && c.trials > 0 {
+		c.trials--
+	}
+}
+
+// refresh changes the open circuit to half-open if the open timeout is reached.
+func (breaker *circuitBreaker) refresh(key string, c *circuit, now time.Time) {
+	if c.state == CircuitOpen && now.Sub(c.openedAt) >= breaker.config.OpenTimeout {
+		breaker.setState(key, c, CircuitHalfOpen, now)
+	}
+}
+
+// setState changes the state of the circuit, and records the change for the state change
+// callback.
+func (breaker *circuitBreaker) setState(key string, c *circuit, state CircuitState, now time.Time) {
+	from := c.state
+
+	c.state = state
+	c.failures = 0
+	c.trials = 0
+	c.successes = 0
+	c.generation++
+	if state == CircuitOpen {
+		c.openedAt = now
+	}
+
+	if breaker.config.OnStateChange != nil {
+		change := circuitStateChange{key: key, from: from, to: state}
+		breaker.changes = append(breaker.changes, change)
+	}
+}
+
+// unlock releases the locker, and calls the state change callback for the changes after that, so
+// the callback can use the client without deadlocks.
+func (breaker *circuitBreaker) unlock() {
+	changes := breaker.changes
+	breaker.changes = nil
+	breaker.mutex.Unlock()
+
+	for _, change := range changes {
+		breaker.config.OnStateChange(change.key, change.from, change.to)
+	}
+}
@@ -0,0 +1,179 @@
+package request
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ghosind/go-assert"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	a := assert.New(t)
+
+	changes := make([]string, 0)
+	mutex := sync.Mutex{}
+	cli := New(Config{
+		BaseURL: "http://127.0.0.1:8080",
+		CircuitBreaker: &CircuitBreakerConfig{
+			FailureThreshold: 2,
+			OpenTimeout:      50 * time.Millisecond,
+			OnStateChange: func(key string, from, to CircuitState) {
+				mutex.Lock()
+				defer mutex.Unlock()
+				changes = append(changes, key+":"+from.String()+"->"+to.String())
+			},
+		},
+	})
+	failOpt := RequestOptions{Parameters: map[string][]string{"status": {"500"}}}
+
+	_, err := cli.GET("/status", failOpt)
+	a.NotNilNow(err)
+	a.NotTrueNow(errors.Is(err, ErrCircuitOpen))
+	a.EqualNow(cli.CircuitState("127.0.0.1:8080"), CircuitClosed)
+
+	// a success resets the consecutive failures
+	_, err = cli.GET("/test")
+	a.NilNow(err)
+	_, err = cli.GET("/status", failOpt)
+	a.NotNilNow(err)
+	a.EqualNow(cli.CircuitState("127.0.0.1:8080"), CircuitClosed)
+
+	_, err = cli.GET("/status", failOpt)
+	a.NotNilNow(err)
+	a.EqualNow(cli.CircuitState("127.0.0.1:8080"), CircuitOpen)
+
+	resp, err := cli.GET("/test")
+	a.NilNow(resp)
+	a.TrueNow(errors.Is(err, ErrCircuitOpen))
+
+	// the other hosts are not affected
+	_, err = cli.GET("http://localhost:8080/test")
+	a.NilNow(err)
+
+	time.Sleep(60 * time.Millisecond)
+	a.EqualNow(cli.CircuitState("127.0.0.1:8080"), CircuitHalfOpen)
+
+	// the failed trial opens the circuit again
+	_, err = cli.GET("/status", failOpt)
+	a.NotNilNow(err)
+	a.NotTrueNow(errors.Is(err, ErrCircuitOpen))
+	a.EqualNow(cli.CircuitState("127.0.0.1:8080"), CircuitOpen)
+
+	time.Sleep(60 * time.Millisecond)
+	_, err = cli.GET("/test")
+	a.NilNow(err)
+	a.EqualNow(cli.CircuitState("127.0.0.1:8080"), CircuitClosed)
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	a.EqualNow(changes, []string{
+		"127.0.0.1:8080:closed->open",
+		"127.0.0.1:8080:open->half-open",
+		"127.0.0.1:8080:half-open->open",
+		"127.0.0.1:8080:open->half-open",
+		"127.0.0.1:8080:half-open->closed",
+	})
+}
+
+func TestCircuitBreakerWithValidateStatus(t *testing.T) {
+	a := assert.New(t)
+
+	cli := New(Config{
+		CircuitBreaker: &CircuitBreakerConfig{FailureThreshold: 1},
+	})
+
+	// the status is valid by the request's validation function
+	_, err := cli.GET("http://127.0.0.1:8080/status", RequestOptions{
+		Parameters:     map[string][]string{"status": {"404"}},
+		ValidateStatus: func(status int) bool { return status < 500 },
+	})
+	a.NilNow(err)
+	a.EqualNow(cli.CircuitState("127.0.0.1:8080"), CircuitClosed)
+
+	_, err = cli.GET("http://127.0.0.1:8080/status", RequestOptions{
+		Parameters: map[string][]string{"status": {"404"}},
+	})
+	a.NotNilNow(err)
+	a.EqualNow(cli.CircuitState("127.0.0.1:8080"), CircuitOpen)
+}
+
+func TestCircuitBreakerWithKeyAndRetry(t *testing.T) {
+	a := assert.New(t)
+
+	attempts := 0
+	cli := New(Config{
+		CircuitBreaker: &CircuitBreakerConfig{
+			FailureThreshold: 2,
+			KeyFunc: func(req *http.Request) string {
+				return req.URL.Path
+			},
+			IsFailure: func(resp *http.Response, err error) bool {
+				return err != nil || resp.StatusCode >= 500
+			},
+		},
+		MaxAttempt: 5,
+		RetryPolicy: &RetryPolicy{
+			ShouldRetry: RetryOnServerErrors,
+			OnRetry: func(attempt int, resp *http.Response, err error, delay time.Duration) {
+				attempts = attempt
+			},
+		},
+	})
+
+	// it stops retrying once the circuit is open
+	_, err := cli.GET("http://127.0.0.1:8080/status", RequestOptions{
+		Parameters: map[string][]string{"status": {"503"}},
+	})
+	a.TrueNow(errors.Is(err, ErrCircuitOpen))
+	a.EqualNow(attempts, 2)
+	a.EqualNow(cli.CircuitState("/status"), CircuitOpen)
+
+	_, err = cli.GET("http://127.0.0.1:8080/test")
+	a.NilNow(err)
+	a.EqualNow(cli.CircuitState("/test"), CircuitClosed)
+}
+
+func TestCircuitBreakerHalfOpenMaxRequests(t *testing.T) {
+	a := assert.New(t)
+
+	breaker := newCircuitBreaker(&CircuitBreakerConfig{
+		FailureThreshold:    1,
+		OpenTimeout:         time.Millisecond,
+		HalfOpenMaxRequests: 2,
+	})
+	a.NilNow(newCircuitBreaker(nil))
+
+	generation, err := breaker.allow("key")
+	a.NilNow(err)
+	breaker.record("key", generation, true)
+	_, err = breaker.allow("key")
+	a.TrueNow(errors.Is(err, ErrCircuitOpen))
+
+	time.Sleep(5 * time.Millisecond)
+	first, err := breaker.allow("key")
+	a.NilNow(err)
+	second, err := breaker.allow("key")
+	a.NilNow(err)
+	_, err = breaker.allow("key")
+	a.TrueNow(errors.Is(err, ErrCircuitOpen))
+
+	// the canceled trial is not counted
+	breaker.release("key", second)
+	second, err = breaker.allow("key")
+	a.NilNow(err)
+
+	breaker.record("key", first, false)
+	a.EqualNow(breaker.circuits["key"].state, CircuitHalfOpen)
+	breaker.record("key", second, false)
+	a.EqualNow(breaker.circuits["key"].state, CircuitClosed)
+
+	// the results of the previous generation are ignored
+	breaker.record("key", second, true)
+	a.EqualNow(breaker.circuits["key"].failures, 0)
+
+	a.EqualNow(New().CircuitState("key"), CircuitClosed)
+	a.EqualNow(CircuitState(10).String(), "unknown(10)")
+}
@@ -1,7 +1,6 @@
 package request
 
 import (
-	"crypto/tls"
 	"net"
 	"net/http"
 	"net/url"
@@ -11,10 +10,30 @@ import (
 
 // Client is the HTTP requesting client.
 type Client struct {
+	// Authenticator adds the credentials to all requests of the client.
+	Authenticator Authenticator
 	// BaseURL will be prepended to all request URL unless URL is absolute.
 	BaseURL string
+	// Cache is the store of the cached responses, it'll not cache the responses if it's nil.
+	Cache CacheStore
+	// ConnectionPool defines the settings of the connection pool for the client's transports. The
+	// transports are created when they're used at the first time, so changing this value will not
+	// affect the existing transports.
+	ConnectionPool *ConnectionPoolConfig
+	// CookieJar is used to store the cookies of the responses and send them with the following
+	// requests, it'll not handle cookies if it's nil.
+	CookieJar http.CookieJar
 	// Headers are custom headers to be sent.
 	Headers map[string][]string
+	// HedgePolicy defines when to send the hedged attempts of the safe requests.
+	HedgePolicy *HedgePolicy
+	// MaxAttempt defines the maximum number of attempts to request, default no retry.
+	MaxAttempt int
+	// MaxBodySize is the maximum number of bytes of the response body, no limitation if it's 0.
+	MaxBodySize int64
+	// MaxCacheBodySize is the maximum number of bytes of the response body that can be cached,
+	// default 10 MB.
+	MaxCacheBodySize int64
 	// MaxRedirects defines the maximum number of redirects for this client, default 5.
 	MaxRedirects int
 	// Parameters are the parameters to be sent.
@@ -23,6 +42,13 @@ type Client struct {
 	ParametersSerializer func(map[string][]string) string
 	// Proxy is the config of the proxy server.
 	Proxy *ProxyConfig
+	// RetryPolicy defines when and how long to wait before re-sending a failed request.
+	RetryPolicy *RetryPolicy
+	// TLS defines the TLS settings for the requests, like the custom root CAs and the client
+	// certificates.
+	TLS *TLSConfig
+	// Signer signs all requests of the client after the request interceptors.
+	Signer Signer
 	// Timeout specifies the time before the request times out.
 	Timeout int
 	// UserAgent sets the client's User-Agent field in the request header.
@@ -33,6 +59,22 @@ type Client struct {
 
 	// clientPool is for save http.Client instances.
 	clientPool *sync.Pool
+	// transports are the cached transports by the proxy and TLS settings.
+	transports map[transportKey]*http.Transport
+	// tlsKeys are the cached keys of the TLS settings.
+	tlsKeys map[*TLSConfig]string
+	// transportMutex is the locker for the transports and the keys of the TLS settings.
+	transportMutex sync.Mutex
+	// bulkhead is the concurrency limiter of the client, it's nil if no limit is set.
+	bulkhead *bulkhead
+	// circuitBreaker is the circuit breaker of the client, it's nil if no circuit breaker is set.
+	circuitBreaker *circuitBreaker
+	// rateLimiter is the rate limiter of the client, it's nil if no rate limit is set.
+	rateLimiter *rateLimiter
+	// codecs are the codecs that are registered to the client.
+	codecs codecRegistry
+	// decompressors are the decompressors that are registered to the client.
+	decompressors decompressorRegistry
 	// reqInterceptors are the request interceptors used for all requests that the client sends.
 	reqInterceptors []requestInterceptor
 	// respInterceptors are the response interceptors used for all requests that the client sends.
@@ -46,11 +88,110 @@ type Client struct {
 
 // Config is the config for the HTTP requesting client.
 type Config struct {
+	// Authenticator adds the credentials to all requests of the client, like `*BasicAuthConfig`,
+	// `*BearerAuth`, `*APIKeyAuth`, and `*DigestAuth`. It will be overwritten by the
+	// authenticator or the basic auth config in the request options.
+	//
+	//	cli := request.New(request.Config{
+	//	  Authenticator: &request.BearerAuth{Token: "XXXXX"},
+	//	})
+	Authenticator Authenticator
 	// BaseURL will be prepended to all request URL unless URL is absolute.
 	BaseURL string
+	// Bulkhead defines the limits of the concurrent in-flight requests of the client, for all the
+	// hosts and for each host. The requests wait in the queue when the limits are reached, and
+	// they'll fail with an `ErrBulkheadFull` error if the queue is full. The slot of a request is
+	// released when its response body is closed.
+	//
+	//	cli := request.New(request.Config{
+	//	  Bulkhead: &request.BulkheadConfig{
+	//	    MaxConcurrentPerHost: 10,
+	//	    MaxQueue:             100,
+	//	  },
+	//	})
+	Bulkhead *BulkheadConfig
+	// Cache is the store of the cached responses, and the responses of the GET requests will be
+	// cached by the HTTP caching semantics if it's set. The cached responses will be served
+	// without sending the requests while they're fresh, and the stale responses will be
+	// revalidated by the `ETag` and the `Last-Modified` fields.
+	//
+	//	cli := request.New(request.Config{
+	//	  Cache: request.NewMemoryCacheStore(100),
+	//	})
+	Cache CacheStore
+	// CircuitBreaker defines the circuit breakers of the client for each host, or each key that is
+	// returned by the key function. The requests will fail with an `ErrCircuitOpen` error without
+	// sending if the circuit is open, and the circuit will be opened after the consecutive
+	// failures that are validated by the same `ValidateStatus` function as the responses.
+	//
+	//	cli := request.New(request.Config{
+	//	  CircuitBreaker: &request.CircuitBreakerConfig{
+	//	    FailureThreshold: 3,
+	//	    OpenTimeout:      10 * time.Second,
+	//	  },
+	//	})
+	CircuitBreaker *CircuitBreakerConfig
+	// ConnectionPool defines the settings of the connection pool for the client's transports, it'll
+	// use the same settings as `http.DefaultTransport` if it's not set.
+	ConnectionPool *ConnectionPoolConfig
+	// Codecs are the custom codecs with their names for encoding the request body and decoding the
+	// response body, and the name can be used as the content type of the requests. They take
+	// precedence over the package-level codecs with the same name or MIME types.
+	//
+	//	cli := request.New(request.Config{
+	//	  Codecs: map[string]request.Codec{
+	//	    "yaml": yamlCodec{},
+	//	  },
+	//	})
+	Codecs map[string]Codec
+	// CookieJar is used to store the cookies of the responses and send them with the following
+	// requests of the client. It can be a `*cookiejar.Jar`, a `*FileCookieJar` that saves the
+	// cookies into a file, or any other implementation of `http.CookieJar`.
+	//
+	//	jar, _ := cookiejar.New(nil)
+	//	cli := request.New(request.Config{
+	//	  CookieJar: jar,
+	//	})
+	CookieJar http.CookieJar
+	// Decompressors are the custom decompressors with their content encodings for decoding the
+	// response bodies, and the content encodings will be added into the `Accept-Encoding` field of
+	// the requests. They take precedence over the package-level decompressors for the same content
+	// encoding.
+	//
+	//	cli := request.New(request.Config{
+	//	  Decompressors: map[string]request.Decompressor{
+	//	    "lz4": lz4Decompressor,
+	//	  },
+	//	})
+	Decompressors map[string]Decompressor
+	// EnableCookies indicates whether to create an in-memory cookie jar for the client if the
+	// `CookieJar` field is not set. The client will not handle cookies by default.
+	EnableCookies bool
 	// Headers are custom headers to be sent, and they'll be overwritten if the
 	// same key is presented in the request.
 	Headers map[string][]string
+	// HedgePolicy defines when to send the hedged attempts of the requests with the safe methods,
+	// it will be overwritten by the request options' hedge policy if it is set. If an attempt
+	// hasn't responded after the delay, another attempt will be sent, and the first successful
+	// response will be used.
+	//
+	//	cli := request.New(request.Config{
+	//	  HedgePolicy: &request.HedgePolicy{
+	//	    Delay:       50 * time.Millisecond,
+	//	    MaxAttempts: 3,
+	//	  },
+	//	})
+	HedgePolicy *HedgePolicy
+	// MaxAttempt defines the maximum number of attempts to request for all requests of the client,
+	// default no retry. It will be overwritten by the request options' max attempt if it is set.
+	MaxAttempt int
+	// MaxBodySize is the maximum number of bytes of the response body after decompression for all
+	// requests of the client. Reading the body fails with a `*BodyTooLargeError` error if the body
+	// is larger than the limit. No limitation if it's 0.
+	MaxBodySize int64
+	// MaxCacheBodySize is the maximum number of bytes of the response body before decompression
+	// that can be stored in the cache, default 10 MB. The larger responses will not be cached.
+	MaxCacheBodySize int64
 	// MaxRedirects defines the maximum number of redirects for this client, default 5.
 	MaxRedirects int
 	// Parameters are the parameters to be sent for all requests of the client. It will be
@@ -65,13 +206,53 @@ type Config struct {
 	// no proxy config in the request options or the client config, the request will try to get a
 	// proxy from the environment variables.
 	Proxy *ProxyConfig
-	// Timeout is request timeout in milliseconds.
+	// RateLimit defines the token-bucket rate limits of the outgoing requests of the client, for
+	// all the hosts and for each host. The requests will wait for the tokens before sending, and
+	// they'll fail with an `ErrRateLimited` error if they can't get the tokens before the
+	// deadline of the request context.
+	//
+	//	cli := request.New(request.Config{
+	//	  RateLimit: &request.RateLimitConfig{
+	//	    PerHostRate:  10,
+	//	    PerHostBurst: 5,
+	//	  },
+	//	})
+	RateLimit *RateLimitConfig
+	// RetryPolicy defines when and how long to wait before re-sending a failed request, it will be
+	// overwritten by the request options' retry policy if it is set. It only retries immediately
+	// when it fails to send the request if no retry policy is set.
+	//
+	//	cli := request.New(request.Config{
+	//	  MaxAttempt: 3,
+	//	  RetryPolicy: &request.RetryPolicy{
+	//	    BaseDelay:   100 * time.Millisecond,
+	//	    ShouldRetry: request.RetryOnServerErrors,
+	//	  },
+	//	})
+	RetryPolicy *RetryPolicy
+	// TLS defines the TLS settings for the requests, like the custom root CAs, the client
+	// certificates for mutual TLS, and the pinned public keys. It will be overwritten by the
+	// request options' TLS config if the TLS config is not empty in the request options.
+	TLS *TLSConfig
+	// Signer signs all requests of the client after the request interceptors, when the headers and
+	// the body of the requests are final. It will be overwritten by the request options' signer.
+	//
+	//	cli := request.New(request.Config{
+	//	  Signer: &request.SigV4Signer{
+	//	    AccessKeyID:     "AKIDEXAMPLE",
+	//	    SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+	//	    Region:          "us-east-1",
+	//	    Service:         "execute-api",
+	//	  },
+	//	})
+	Signer Signer
+	// Timeout is request timeout in milliseconds, including reading the response body.
 	Timeout int
 	// UserAgent sets the client's User-Agent field in the request header.
 	UserAgent string
 	// ValidateStatus defines whether the status code of the response is valid or not, and it'll
-	// return an error if fails to validate the status code. Default, it sets the result to fail if
-	// the status code is less than 200, or greater than and equal to 400.
+	// return a `*ResponseError` if fails to validate the status code. Default, it sets the result
+	// to fail if the status code is less than 200, or greater than and equal to 400.
 	//
 	//	cli := request.New(request.Config{
 	//	  ValidateStatus: func (status int) bool {
@@ -122,16 +303,37 @@ func New(config ...Config) *Client {
 	if len(config) > 0 {
 		cfg := config[0]
 
+		cli.Authenticator = cfg.Authenticator
 		cli.BaseURL = cfg.BaseURL
+		cli.bulkhead = newBulkhead(cfg.Bulkhead)
+		cli.Cache = cfg.Cache
+		cli.circuitBreaker = newCircuitBreaker(cfg.CircuitBreaker)
+		cli.ConnectionPool = cfg.ConnectionPool
+		cli.CookieJar = getCookieJar(cfg)
+		cli.HedgePolicy = cfg.HedgePolicy
+		cli.MaxAttempt = cfg.MaxAttempt
+		cli.MaxBodySize = cfg.MaxBodySize
+		cli.MaxCacheBodySize = cfg.MaxCacheBodySize
 		cli.MaxRedirects = cfg.MaxRedirects
 		cli.ParametersSerializer = cfg.ParametersSerializer
 		cli.Proxy = cfg.Proxy
+		cli.rateLimiter = newRateLimiter(cfg.RateLimit)
+		cli.RetryPolicy = cfg.RetryPolicy
+		cli.Signer = cfg.Signer
+		cli.TLS = cfg.TLS
 		cli.Timeout = cfg.Timeout
 		cli.UserAgent = cfg.UserAgent
 		cli.ValidateStatus = cfg.ValidateStatus
 
 		cli.initClientHeaders(cfg.Headers)
 		cli.initClientParameters(cfg.Parameters)
+
+		for name, codec := range cfg.Codecs {
+			cli.RegisterCodec(name, codec)
+		}
+		for encoding, decompressor := range cfg.Decompressors {
+			cli.RegisterDecompressor(encoding, decompressor)
+		}
 	}
 
 	return cli
@@ -344,7 +546,12 @@ func (cli *Client) initClientParameters(parameters map[string][]string) {
 }
 
 // getHTTPClient gets an `http.Client` from the pool, and resets it to default state.
-func (cli *Client) getHTTPClient(opt RequestOptions) *http.Client {
+func (cli *Client) getHTTPClient(opt RequestOptions) (*http.Client, error) {
+	transport, err := cli.getTransport(opt)
+	if err != nil {
+		return nil, err
+	}
+
 	if cli.clientPool == nil {
 		cli.clientPool = &sync.Pool{
 			New: func() any {
@@ -364,9 +571,10 @@ func (cli *Client) getHTTPClient(opt RequestOptions) *http.Client {
 	}
 
 	httpClient.CheckRedirect = cli.getCheckRedirect(maxRedirects)
-	httpClient.Transport = cli.getTransport(opt)
+	httpClient.Jar = cli.CookieJar
+	httpClient.Transport = transport
 
-	return httpClient
+	return httpClient, nil
 }
 
 // getCheckRedirect returns a new check redirects handler for `http.Client`. This function will
@@ -406,24 +614,9 @@ func (cli *Client) defaultValidateStatus(status int) bool {
 	return status >= http.StatusOK && status < http.StatusBadRequest
 }
 
-// getTransport gets the transport by the request options.
-func (cli *Client) getTransport(opt RequestOptions) http.RoundTripper {
-	proxy := cli.getProxy(opt)
-	tls := cli.getTLSConfig(opt)
-
-	if proxy == nil && tls == nil {
-		return nil
-	}
-
-	return &http.Transport{
-		Proxy:           proxy,
-		TLSClientConfig: tls,
-	}
-}
-
-// getProxy tries to get a proxy by the proxy config from the request options, and it returns
-// `http.ProxyFromEnvironment` if there is no proxy config in the request options.
-func (cli *Client) getProxy(opt RequestOptions) func(*http.Request) (*url.URL, error) {
+// getProxyURL tries to get the URL of the proxy server by the proxy config from the request
+// options or the client config, and it returns nil if there is no proxy config.
+func (cli *Client) getProxyURL(opt RequestOptions) *url.URL {
 	proxy := opt.Proxy
 	if proxy == nil {
 		proxy = cli.Proxy
@@ -439,17 +632,5 @@ func (cli *Client) getProxy(opt RequestOptions) func(*http.Request) (*url.URL, e
 		proxyUrl.User = url.UserPassword(proxy.Username, proxy.Password)
 	}
 
-	return http.ProxyURL(proxyUrl)
-}
-
-// getTLSConfig tries to get the config that used to configure a TLS client or server.
-func (cli *Client) getTLSConfig(opt RequestOptions) *tls.Config {
-	if !opt.InsecureSkipVerify {
-		return nil
-	}
-
-	cfg := new(tls.Config)
-	cfg.InsecureSkipVerify = opt.InsecureSkipVerify
-
-	return cfg
+	return proxyUrl
 }
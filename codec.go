@@ -0,0 +1,235 @@
+package request
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Codec is the encoder and decoder for a type of content, it's used to encode the request body
+// and decode the response body.
+//
+//	type yamlCodec struct{}
+//
+//	func (yamlCodec) Marshal(v any) ([]byte, error) { return yaml.Marshal(v) }
+//	func (yamlCodec) Unmarshal(data []byte, v any) error { return yaml.Unmarshal(data, v) }
+//	func (yamlCodec) MIMETypes() []string { return []string{"application/yaml", "text/yaml"} }
+//
+//	request.RegisterCodec("yaml", yamlCodec{})
+type Codec interface {
+	// Marshal encodes the value into bytes.
+	Marshal(v any) ([]byte, error)
+	// Unmarshal decodes the data into the value.
+	Unmarshal(data []byte, v any) error
+	// MIMETypes returns the MIME types that the codec can handle. The first MIME type will be used
+	// as the value of the `Content-Type` field in the request headers.
+	MIMETypes() []string
+}
+
+// codecRegistry is a collection of the codecs, and it can find a codec by its name or the MIME
+// type.
+type codecRegistry struct {
+	// codecs are the registered codecs with their names.
+	codecs map[string]Codec
+	// mimeTypes are the registered codecs with their MIME types.
+	mimeTypes map[string]Codec
+	// mutex is the locker for the registry.
+	mutex sync.RWMutex
+}
+
+// clientContextKey is the key for the client in the request context.
+type clientContextKey struct{}
+
+// defaultCodecs is the package-level codec registry, and it contains the built-in codecs.
+var defaultCodecs *codecRegistry
+
+func init() {
+	defaultCodecs = new(codecRegistry)
+	defaultCodecs.register(RequestContentTypeJSON, jsonCodec{})
+	defaultCodecs.register(RequestContentTypeForm, formCodec{})
+	defaultCodecs.register(RequestContentTypeXML, xmlCodec{})
+}
+
+// RegisterCodec registers the codec with the name to the package-level registry, and all clients
+// can use it by setting the name as the content type of the requests. It'll overwrite the codec
+// with the same name or the same MIME types.
+//
+//	request.RegisterCodec("yaml", yamlCodec{})
+//
+//	resp, err := request.POST("http://example.com", request.RequestOptions{
+//	  ContentType: "yaml",
+//	  Body:        data,
+//	})
+func RegisterCodec(name string, codec Codec) {
+	defaultCodecs.register(name, codec)
+}
+
+// RegisterCodec registers the codec with the name to the client, and it's only available for the
+// requests sent by this client. The codecs registered to the client take precedence over the
+// package-level codecs with the same name or the same MIME types.
+//
+//	cli := request.New()
+//	cli.RegisterCodec("yaml", yamlCodec{})
+func (cli *Client) RegisterCodec(name string, codec Codec) {
+	cli.codecs.register(name, codec)
+}
+
+// getCodec gets the codec by the name from the client's codecs or the package-level codecs.
+func (cli *Client) getCodec(name string) (Codec, bool) {
+	name = strings.ToLower(name)
+
+	if cli != nil {
+		if codec, ok := cli.codecs.get(name); ok {
+			return codec, true
+		}
+	}
+
+	return defaultCodecs.get(name)
+}
+
+// getResponseCodec gets the codec for decoding the response body by the value of the
+// `Content-Type` field in the response headers. It'll use the JSON codec if no codec matches the
+// content type.
+func (cli *Client) getResponseCodec(contentType string) Codec {
+	mimeType, _, err := mime.ParseMediaType(contentType)
+	if err == nil {
+		if cli != nil {
+			if codec, ok := cli.codecs.getByMIMEType(mimeType); ok {
+				return codec
+			}
+		}
+		if codec, ok := defaultCodecs.getByMIMEType(mimeType); ok {
+			return codec
+		}
+	}
+
+	if codec, ok := cli.getCodec(getContentType(contentType)); ok {
+		return codec
+	}
+
+	codec, _ := cli.getCodec(RequestContentTypeJSON)
+	return codec
+}
+
+// getClientFromResponse gets the client that sent the request of the response.
+func getClientFromResponse(resp *http.Response) *Client {
+	if resp == nil || resp.Request == nil {
+		return nil
+	}
+
+	cli, _ := resp.Request.Context().Value(clientContextKey{}).(*Client)
+	return cli
+}
+
+// withClientContext returns a copy of the context with the client.
+func (cli *Client) withClientContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, clientContextKey{}, cli)
+}
+
+// register adds the codec with the name into the registry.
+func (registry *codecRegistry) register(name string, codec Codec) {
+	if codec == nil {
+		return
+	}
+
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	if registry.codecs == nil {
+		registry.codecs = make(map[string]Codec)
+		registry.mimeTypes = make(map[string]Codec)
+	}
+
+	registry.codecs[strings.ToLower(name)] = codec
+	for _, mimeType := range codec.MIMETypes() {
+		registry.mimeTypes[strings.ToLower(mimeType)] = codec
+	}
+}
+
+// get gets the codec by the name.
+func (registry *codecRegistry) get(name string) (Codec, bool) {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+
+	codec, ok := registry.codecs[name]
+	return codec, ok
+}
+
+// getByMIMEType gets the codec by the MIME type.
+func (registry *codecRegistry) getByMIMEType(mimeType string) (Codec, bool) {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+
+	codec, ok := registry.mimeTypes[strings.ToLower(mimeType)]
+	return codec, ok
+}
+
+// jsonCodec is the built-in codec for JSON.
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+func (jsonCodec) MIMETypes() []string {
+	return []string{"application/json"}
+}
+
+// xmlCodec is the built-in codec for XML.
+type xmlCodec struct{}
+
+func (xmlCodec) Marshal(v any) ([]byte, error) {
+	return xml.Marshal(v)
+}
+
+func (xmlCodec) Unmarshal(data []byte, v any) error {
+	return xml.Unmarshal(data, v)
+}
+
+func (xmlCodec) MIMETypes() []string {
+	return []string{"application/xml", "text/xml"}
+}
+
+// formCodec is the built-in codec for the form-urlencoded content, and it can only decode the
+// data into `url.Values`, `map[string][]string`, or `map[string]string`.
+type formCodec struct{}
+
+func (formCodec) Marshal(v any) ([]byte, error) {
+	return encodeForm(v)
+}
+
+func (formCodec) Unmarshal(data []byte, v any) error {
+	values, err := url.ParseQuery(string(data))
+	if err != nil {
+		return err
+	}
+
+	switch out := v.(type) {
+	case *url.Values:
+		*out = values
+	case *map[string][]string:
+		*out = values
+	case *map[string]string:
+		*out = make(map[string]string, len(values))
+		for k := range values {
+			(*out)[k] = values.Get(k)
+		}
+	default:
+		return ErrUnsupportedType
+	}
+
+	return nil
+}
+
+func (formCodec) MIMETypes() []string {
+	return []string{"application/x-www-form-urlencoded"}
+}
@@ -0,0 +1,147 @@
+package request
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/ghosind/go-assert"
+)
+
+type testTextCodec struct {
+	mimeType string
+}
+
+func (codec testTextCodec) Marshal(v any) ([]byte, error) {
+	s, ok := v.(*testTextData)
+	if !ok {
+		return nil, ErrUnsupportedType
+	}
+
+	return []byte(strings.Join(s.Lines, "\n")), nil
+}
+
+func (codec testTextCodec) Unmarshal(data []byte, v any) error {
+	s, ok := v.(*testTextData)
+	if !ok {
+		return ErrUnsupportedType
+	}
+
+	s.Lines = strings.Split(string(data), "\n")
+	return nil
+}
+
+func (codec testTextCodec) MIMETypes() []string {
+	return []string{codec.mimeType}
+}
+
+type testTextData struct {
+	Lines []string
+}
+
+func TestClientCodec(t *testing.T) {
+	a := assert.New(t)
+
+	cli := New(Config{
+		Codecs: map[string]Codec{
+			"Lines": testTextCodec{mimeType: "text/x-lines"},
+		},
+	})
+
+	data, _, err := ToObject[testResponse](cli.POST("http://localhost:8080", RequestOptions{
+		ContentType: "lines",
+		Body:        &testTextData{Lines: []string{"Hello", "world"}},
+	}))
+	a.NilNow(err)
+	a.NotNilNow(data.ContentType)
+	a.NotNilNow(data.Body)
+	a.EqualNow(*data.ContentType, "text/x-lines")
+	a.EqualNow(*data.Body, "Hello\nworld")
+
+	// not available for other clients
+	_, err = POST("http://localhost:8080", RequestOptions{
+		ContentType: "lines",
+		Body:        &testTextData{Lines: []string{"Hello", "world"}},
+	})
+	a.NotNilNow(err)
+
+	req, err := http.NewRequestWithContext(
+		cli.withClientContext(context.Background()),
+		http.MethodGet,
+		"http://localhost:8080",
+		nil,
+	)
+	a.NilNow(err)
+
+	out, _, err := ToObject[testTextData](&http.Response{
+		Header:  http.Header{"Content-Type": {"text/x-lines; charset=utf-8"}},
+		Body:    io.NopCloser(bytes.NewReader([]byte("a\nb"))),
+		Request: req,
+	}, nil)
+	a.NilNow(err)
+	a.EqualNow(out.Lines, []string{"a", "b"})
+}
+
+func TestPackageLevelCodec(t *testing.T) {
+	a := assert.New(t)
+
+	RegisterCodec("test-lines", testTextCodec{mimeType: "text/x-test-lines"})
+	defer func() {
+		defaultCodecs.mutex.Lock()
+		delete(defaultCodecs.codecs, "test-lines")
+		delete(defaultCodecs.mimeTypes, "text/x-test-lines")
+		defaultCodecs.mutex.Unlock()
+	}()
+
+	cli := New()
+	data, _, err := ToObject[testResponse](cli.POST("http://localhost:8080", RequestOptions{
+		ContentType: "test-lines",
+		Body:        &testTextData{Lines: []string{"Hello", "world"}},
+	}))
+	a.NilNow(err)
+	a.EqualNow(*data.ContentType, "text/x-test-lines")
+	a.EqualNow(*data.Body, "Hello\nworld")
+
+	out, _, err := ToObject[testTextData](&http.Response{
+		Header: http.Header{"Content-Type": {"text/x-test-lines"}},
+		Body:   io.NopCloser(bytes.NewReader([]byte("a\nb"))),
+	}, nil)
+	a.NilNow(err)
+	a.EqualNow(out.Lines, []string{"a", "b"})
+}
+
+func TestGetResponseCodec(t *testing.T) {
+	a := assert.New(t)
+	cli := New()
+
+	a.EqualNow(cli.getResponseCodec(""), Codec(jsonCodec{}))
+	a.EqualNow(cli.getResponseCodec("application/json"), Codec(jsonCodec{}))
+	a.EqualNow(cli.getResponseCodec("application/vnd.github+json"), Codec(jsonCodec{}))
+	a.EqualNow(cli.getResponseCodec("text/xml; charset=utf-8"), Codec(xmlCodec{}))
+	a.EqualNow(cli.getResponseCodec("application/x-www-form-urlencoded"), Codec(formCodec{}))
+	a.EqualNow(cli.getResponseCodec("text/html"), Codec(jsonCodec{}))
+
+	var nilClient *Client
+	a.EqualNow(nilClient.getResponseCodec("text/xml"), Codec(xmlCodec{}))
+}
+
+func TestFormCodecUnmarshal(t *testing.T) {
+	a := assert.New(t)
+	codec := formCodec{}
+
+	values := url.Values{}
+	a.NilNow(codec.Unmarshal([]byte("a=1&a=2&b=3"), &values))
+	a.EqualNow(values, url.Values{"a": {"1", "2"}, "b": {"3"}})
+
+	m := map[string]string{}
+	a.NilNow(codec.Unmarshal([]byte("a=1&a=2&b=3"), &m))
+	a.EqualNow(m, map[string]string{"a": "1", "b": "3"})
+
+	var s struct{}
+	a.NotNilNow(codec.Unmarshal([]byte("a=1"), &s))
+	a.NotNilNow(codec.Unmarshal([]byte("%zz"), &values))
+}
@@ -0,0 +1,262 @@
+package request
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileCookieJar is a cookie jar that can save the cookies into a file and load them from the file,
+// to keep the cookies between process runs. It manages the cookies with an in-memory
+// `cookiejar.Jar`, and the cookies will not be written into the file until the `Save` method is
+// called.
+//
+//	jar, err := request.NewFileCookieJar("cookies.json")
+//	if err != nil {
+//	  // Error handling
+//	}
+//	defer jar.Save()
+//
+//	cli := request.New(request.Config{
+//	  CookieJar: jar,
+//	})
+type FileCookieJar struct {
+	// path is the path of the file to save the cookies.
+	path string
+	// jar is the in-memory cookie jar.
+	jar *cookiejar.Jar
+	// entries are the cookies that were set into the jar, by the domain, the path, and the name of
+	// the cookies.
+	entries map[string]fileCookieEntry
+	// mutex is the locker for the entries.
+	mutex sync.Mutex
+}
+
+// fileCookieEntry is a cookie that is saved in the file, and the URL is the address of the
+// response that set the cookie.
+type fileCookieEntry struct {
+	URL      string        `json:"url"`
+	Name     string        `json:"name"`
+	Value    string        `json:"value"`
+	Path     string        `json:"path,omitempty"`
+	Domain   string        `json:"domain,omitempty"`
+	Expires  time.Time     `json:"expires,omitempty"`
+	Secure   bool          `json:"secure,omitempty"`
+	HttpOnly bool          `json:"httpOnly,omitempty"`
+	SameSite http.SameSite `json:"sameSite,omitempty"`
+}
+
+// NewFileCookieJar creates a new cookie jar that saves the cookies into the file, and it loads the
+// cookies from the file if the file exists.
+func NewFileCookieJar(path string) (*FileCookieJar, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	fileJar := &FileCookieJar{
+		path:    path,
+		jar:     jar,
+		entries: make(map[string]fileCookieEntry),
+	}
+
+	if err := fileJar.Load(); err != nil {
+		return nil, err
+	}
+
+	return fileJar, nil
+}
+
+// SetCookies handles the receipt of the cookies in a reply for the given URL.
+func (jar *FileCookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	jar.mutex.Lock()
+	defer jar.mutex.Unlock()
+
+	jar.setCookies(u, cookies, time.Now())
+}
+
+// Cookies returns the cookies to send in a request for the given URL.
+func (jar *FileCookieJar) Cookies(u *url.URL) []*http.Cookie {
+	return jar.jar.Cookies(u)
+}
+
+// Load reads the cookies from the file into the jar, and the expired cookies will be dropped. It
+// does nothing if the file does not exist.
+func (jar *FileCookieJar) Load() error {
+	data, err := os.ReadFile(jar.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	entries := make([]fileCookieEntry, 0)
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return err
+	}
+
+	jar.mutex.Lock()
+	defer jar.mutex.Unlock()
+
+	now := time.Now()
+	for _, entry := range entries {
+		u, err := url.Parse(entry.URL)
+		if err != nil {
+			continue
+		}
+
+		jar.setCookies(u, []*http.Cookie{entry.cookie()}, now)
+	}
+
+	return nil
+}
+
+// Save writes the unexpired cookies in the jar into the file, and the file will be created if it
+// does not exist. The session cookies, which have no expiration time, are also saved.
+func (jar *FileCookieJar) Save() error {
+	jar.mutex.Lock()
+	now := time.Now()
+	entries := make([]fileCookieEntry, 0, len(jar.entries))
+	for key, entry := range jar.entries {
+		if !entry.Expires.IsZero() && !entry.Expires.After(now) {
+			delete(jar.entries, key)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	jar.mutex.Unlock()
+
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(jar.path), filepath.Base(jar.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), jar.path)
+}
+
+// setCookies sets the cookies into the in-memory jar, and records them for saving.
+func (jar *FileCookieJar) setCookies(u *url.URL, cookies []*http.Cookie, now time.Time) {
+	jar.jar.SetCookies(u, cookies)
+
+	for _, cookie := range cookies {
+		entry := newFileCookieEntry(u, cookie, now)
+		key := entry.key()
+
+		if cookie.MaxAge < 0 || (!entry.Expires.IsZero() && !entry.Expires.After(now)) {
+			delete(jar.entries, key)
+		} else {
+			jar.entries[key] = entry
+		}
+	}
+}
+
+// newFileCookieEntry creates an entry for the cookie from the URL, and the max age of the cookie
+// is converted to the expiration time.
+func newFileCookieEntry(u *url.URL, cookie *http.Cookie, now time.Time) fileCookieEntry {
+	entry := fileCookieEntry{
+		URL:      (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: u.Path}).String(),
+		Name:     cookie.Name,
+		Value:    cookie.Value,
+		Path:     cookie.Path,
+		Domain:   cookie.Domain,
+		Expires:  cookie.Expires,
+		Secure:   cookie.Secure,
+		HttpOnly: cookie.HttpOnly,
+		SameSite: cookie.SameSite,
+	}
+	if cookie.MaxAge > 0 {
+		entry.Expires = now.Add(time.Duration(cookie.MaxAge) * time.Second)
+	}
+
+	return entry
+}
+
+// key returns the identity of the cookie by its domain, path and name.
+func (entry fileCookieEntry) key() string {
+	domain := strings.TrimPrefix(strings.ToLower(entry.Domain), ".")
+	if domain == "" {
+		if u, err := url.Parse(entry.URL); err == nil {
+			domain = strings.ToLower(u.Hostname())
+		}
+	}
+
+	path := entry.Path
+	if path == "" || path[0] != '/' {
+		path = defaultCookiePath(entry.URL)
+	}
+
+	return domain + ";" + path + ";" + entry.Name
+}
+
+// defaultCookiePath returns the default path of the cookie without the path attribute, which is
+// the directory of the URL path (RFC 6265 section 5.1.4).
+func defaultCookiePath(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Path == "" || u.Path[0] != '/' {
+		return "/"
+	}
+
+	i := strings.LastIndex(u.Path, "/")
+	if i == 0 {
+		return "/"
+	}
+
+	return u.Path[:i]
+}
+
+// cookie converts the entry to an `http.Cookie`.
+func (entry fileCookieEntry) cookie() *http.Cookie {
+	return &http.Cookie{
+		Name:     entry.Name,
+		Value:    entry.Value,
+		Path:     entry.Path,
+		Domain:   entry.Domain,
+		Expires:  entry.Expires,
+		Secure:   entry.Secure,
+		HttpOnly: entry.HttpOnly,
+		SameSite: entry.SameSite,
+	}
+}
+
+// getCookieJar returns the cookie jar of the client, and it creates an in-memory cookie jar if
+// the cookies are enabled without a custom cookie jar.
+func getCookieJar(cfg Config) http.CookieJar {
+	if cfg.CookieJar != nil {
+		return cfg.CookieJar
+	} else if !cfg.EnableCookies {
+		return nil
+	}
+
+	jar, _ := cookiejar.New(nil)
+	return jar
+}
+
+// setCookies adds the cookies in the request options to the request headers.
+func setCookies(req *http.Request, opt RequestOptions) {
+	for _, cookie := range opt.Cookies {
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+	}
+}
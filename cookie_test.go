@@ -0,0 +1,96 @@
+package request
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ghosind/go-assert"
+)
+
+func TestClientCookieJar(t *testing.T) {
+	a := assert.New(t)
+
+	cli := New()
+	a.NilNow(cli.CookieJar)
+
+	cli = New(Config{EnableCookies: true})
+	a.NotNilNow(cli.CookieJar)
+
+	_, _, err := ToObject[testResponse](cli.GET("http://127.0.0.1:8080/cookie?session=abc"))
+	a.NilNow(err)
+
+	data, _, err := ToObject[testResponse](cli.GET("http://127.0.0.1:8080/test"))
+	a.NilNow(err)
+	a.EqualNow((*data.Headers)["Cookie"], []string{"session=abc"})
+
+	// cookies are not shared between clients
+	data, _, err = ToObject[testResponse](New().GET("http://127.0.0.1:8080/test"))
+	a.NilNow(err)
+	a.NilNow((*data.Headers)["Cookie"])
+}
+
+func TestRequestCookies(t *testing.T) {
+	a := assert.New(t)
+
+	data, _, err := ToObject[testResponse](Req("http://127.0.0.1:8080/test").
+		AddCookie(&http.Cookie{Name: "a", Value: "1"}, &http.Cookie{Name: "b", Value: "2"}).
+		AddCookie(nil).
+		Do())
+	a.NilNow(err)
+	a.EqualNow((*data.Headers)["Cookie"], []string{"a=1; b=2"})
+}
+
+func TestFileCookieJar(t *testing.T) {
+	a := assert.New(t)
+	path := filepath.Join(t.TempDir(), "cookies.json")
+
+	jar, err := NewFileCookieJar(path)
+	a.NilNow(err)
+
+	cli := New(Config{CookieJar: jar})
+	_, err = cli.GET("http://127.0.0.1:8080/cookie?session=abc&maxAge=3600")
+	a.NilNow(err)
+	_, err = cli.GET("http://127.0.0.1:8080/cookie?token=xyz")
+	a.NilNow(err)
+
+	u, _ := url.Parse("http://127.0.0.1:8080/")
+	jar.SetCookies(u, []*http.Cookie{
+		{Name: "expired", Value: "1", Expires: time.Now().Add(-time.Hour)},
+		{Name: "token", Value: "", MaxAge: -1},
+	})
+	a.NilNow(jar.Save())
+
+	loaded, err := NewFileCookieJar(path)
+	a.NilNow(err)
+	cookies := loaded.Cookies(u)
+	a.EqualNow(len(cookies), 1)
+	a.EqualNow(cookies[0].Name, "session")
+	a.EqualNow(cookies[0].Value, "abc")
+
+	cli = New(Config{CookieJar: loaded})
+	data, _, err := ToObject[testResponse](cli.GET("http://127.0.0.1:8080/test"))
+	a.NilNow(err)
+	a.EqualNow((*data.Headers)["Cookie"], []string{"session=abc"})
+}
+
+func TestFileCookieJarWithInvalidFile(t *testing.T) {
+	a := assert.New(t)
+	dir := t.TempDir()
+
+	jar, err := NewFileCookieJar(filepath.Join(dir, "not-exists.json"))
+	a.NilNow(err)
+	a.NotNilNow(jar)
+
+	path := filepath.Join(dir, "invalid.json")
+	a.NilNow(os.WriteFile(path, []byte("invalid"), 0600))
+	_, err = NewFileCookieJar(path)
+	a.NotNilNow(err)
+
+	jar, err = NewFileCookieJar(filepath.Join(dir, "not-exists", "cookies.json"))
+	a.NilNow(err)
+	a.NotNilNow(jar.Save())
+}
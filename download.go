@@ -0,0 +1,559 @@
+package request
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// downloadStateInterval is the interval of saving the state of the download while transferring.
+const downloadStateInterval = time.Second
+
+// DownloadOptions is the options for downloading a file.
+type DownloadOptions struct {
+	// RequestOptions is the options of the requests for downloading the file. The method of the
+	// requests is always `GET`, and the timeout is the limit of every request including reading
+	// the body, default no limit.
+	RequestOptions RequestOptions
+	// Segments is the number of the ranged requests to download the file in parallel, default 1.
+	// The file will be downloaded by a single request if the server doesn't support the range
+	// requests or the size of the file is unknown.
+	Segments int
+	// Checksum is the expected checksum of the file in the hex encoding. The downloaded file will
+	// be removed and an `ErrChecksumMismatch` error will be returned if the checksum of the file is
+	// different.
+	Checksum string
+	// Hash is the function to create the hash for computing the checksum, default `sha256.New`.
+	Hash func() hash.Hash
+	// OnProgress is the function to receive the progress of the download, and it's called at most
+	// every 100 milliseconds and once more when the download is completed. The progress includes
+	// the bytes that were downloaded before resuming, but the rate doesn't.
+	OnProgress func(progress Progress)
+}
+
+// download is the state of downloading a file.
+type download struct {
+	cli  *Client
+	url  string
+	path string
+	opt  DownloadOptions
+	// state is the state of the download that will be saved for resuming.
+	state *downloadState
+	// file is the temporary file of the download.
+	file *os.File
+	// tracker reports the progress of the download.
+	tracker *progressTracker
+	// mutex is the locker for the written bytes of the segments.
+	mutex sync.Mutex
+}
+
+// downloadState is the state of the download that is saved for resuming the interrupted transfer.
+type downloadState struct {
+	// URL is the URL of the file.
+	URL string `json:"url"`
+	// ETag is the entity tag of the file.
+	ETag string `json:"etag,omitempty"`
+	// LastModified is the last modification time of the file.
+	LastModified string `json:"lastModified,omitempty"`
+	// Size is the size of the file, and it's -1 if the size is unknown.
+	Size int64 `json:"size"`
+	// Segments are the ranges of the file to download.
+	Segments []*downloadSegment `json:"segments"`
+}
+
+// resumable checks whether the download can be resumed by the range requests with the `If-Range`
+// field, it requires a strong entity tag or the last modification time of the file.
+func (state *downloadState) resumable() bool {
+	return state.LastModified != "" || (state.ETag != "" && !strings.HasPrefix(state.ETag, "W/"))
+}
+
+// downloadSegment is a range of the file to download.
+type downloadSegment struct {
+	// Start is the offset of the first byte of the range.
+	Start int64 `json:"start"`
+	// End is the offset of the last byte of the range, and it's -1 if the size is unknown.
+	End int64 `json:"end"`
+	// Written is the number of bytes that have been written.
+	Written int64 `json:"written"`
+}
+
+// Download downloads the file of the URL into the path, and returns the size of the file. The data
+// is written into the temporary file "<path>.download" first, and the temporary file will be
+// renamed to the path after the whole file is downloaded and verified. If the download is
+// interrupted, the temporary file and the state file "<path>.download.json" are kept, and the
+// next download of the same URL and path resumes the transfer by the range requests. The
+// `If-Range` header with the entity tag or the last modification time is sent for resuming, and
+// the download restarts from the beginning if the file has been changed on the server. The
+// temporary file is removed if the download can't be resumed because the server provides neither
+// a strong entity tag nor the last modification time.
+//
+//	n, err := cli.Download("https://example.com/file.zip", "file.zip", request.DownloadOptions{
+//	  Segments: 4,
+//	  Checksum: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+//	  OnProgress: func(progress request.Progress) {
+//	    log.Printf("%d/%d", progress.Transferred, progress.Total)
+//	  },
+//	})
+func (cli *Client) Download(url, path string, opts ...DownloadOptions) (int64, error) {
+	d := &download{
+		cli:  cli,
+		url:  url,
+		path: path,
+	}
+	if len(opts) > 0 {
+		d.opt = opts[0]
+	}
+
+	return d.run()
+}
+
+// run downloads the file, and it restarts the download once if the file has been changed. It
+// returns an `ErrFileChanged` error if the file is changed again.
+func (d *download) run() (int64, error) {
+	d.loadState()
+
+	for restarted := false; ; restarted = true {
+		err := d.open()
+		if err == nil {
+			err = d.transfer(d.context())
+		}
+		if err == ErrFileChanged && !restarted {
+			d.clean()
+			continue
+		} else if err != nil {
+			if d.state != nil && d.state.resumable() {
+				d.close()
+			} else {
+				d.clean()
+			}
+			return 0, err
+		}
+
+		break
+	}
+
+	size, err := d.finish()
+	if err != nil {
+		return 0, err
+	}
+
+	return size, nil
+}
+
+// tempPath returns the path of the temporary file.
+func (d *download) tempPath() string {
+	return d.path + ".download"
+}
+
+// statePath returns the path of the state file.
+func (d *download) statePath() string {
+	return d.path + ".download.json"
+}
+
+// loadState loads the state of the interrupted download, and it ignores the state if it's not
+// resumable.
+func (d *download) loadState() {
+	data, err := os.ReadFile(d.statePath())
+	if err != nil {
+		return
+	}
+
+	state := new(downloadState)
+	if err := json.Unmarshal(data, state); err != nil || state.URL != d.url ||
+		!state.resumable() || len(state.Segments) == 0 {
+		return
+	}
+	if _, err := os.Stat(d.tempPath()); err != nil {
+		return
+	}
+
+	d.state = state
+}
+
+// saveState saves the state of the download for resuming, and it removes the state file if the
+// download is not resumable.
+func (d *download) saveState() {
+	d.mutex.Lock()
+	if d.state == nil || !d.state.resumable() {
+		d.mutex.Unlock()
+		os.Remove(d.statePath())
+		return
+	}
+	data, err := json.Marshal(d.state)
+	d.mutex.Unlock()
+	if err != nil {
+		return
+	}
+
+	os.WriteFile(d.statePath(), data, 0o644)
+}
+
+// open opens the temporary file, and creates the state of the download if it's a new download.
+func (d *download) open() error {
+	if d.state != nil {
+		file, err := os.OpenFile(d.tempPath(), os.O_WRONLY, 0o644)
+		if err != nil {
+			return err
+		}
+		d.file = file
+		return nil
+	}
+
+	file, err := os.OpenFile(d.tempPath(), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	d.file = file
+
+	d.state = &downloadState{
+		URL:      d.url,
+		Size:     -1,
+		Segments: []*downloadSegment{{Start: 0, End: -1}},
+	}
+	if d.opt.Segments > 1 {
+		if err := d.split(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// split gets the size of the file by a HEAD request, and splits the file into the segments if
+// the server supports the range requests and the file can be validated by the `If-Range` field.
+func (d *download) split() error {
+	opt := d.requestOptions()
+	opt.Method = http.MethodHead
+	ctx, canFunc := d.cli.withTimeout(d.context(), opt)
+	defer canFunc()
+	opt.Context = ctx
+
+	resp, err := d.cli.request(http.MethodHead, d.url, opt)
+	if err != nil {
+		closeResponseBody(resp)
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.Header.Get("Accept-Ranges") != "bytes" || resp.ContentLength <= 0 {
+		return nil
+	}
+
+	d.setValidators(resp)
+	if !d.state.resumable() {
+		// the segments may be the different versions of the file without the validator.
+		return nil
+	}
+	d.state.Size = resp.ContentLength
+
+	segments := int64(d.opt.Segments)
+	if segments > d.state.Size {
+		segments = d.state.Size
+	}
+	segmentSize := d.state.Size / segments
+	d.state.Segments = make([]*downloadSegment, 0, segments)
+	for i := int64(0); i < segments; i++ {
+		segment := &downloadSegment{Start: i * segmentSize, End: (i+1)*segmentSize - 1}
+		if i == segments-1 {
+			segment.End = d.state.Size - 1
+		}
+		d.state.Segments = append(d.state.Segments, segment)
+	}
+
+	return nil
+}
+
+// context returns the parent context of the requests.
+func (d *download) context() context.Context {
+	if d.opt.RequestOptions.Context != nil {
+		return d.opt.RequestOptions.Context
+	}
+	return context.Background()
+}
+
+// requestOptions returns the request options for downloading the file.
+func (d *download) requestOptions() RequestOptions {
+	opt := d.opt.RequestOptions
+	opt.Method = http.MethodGet
+	opt.DisableCache = true
+	if opt.Timeout == 0 {
+		opt.Timeout = RequestTimeoutNoLimit
+	}
+
+	headers := make(map[string][]string, len(opt.Headers)+3)
+	for k, v := range opt.Headers {
+		headers[http.CanonicalHeaderKey(k)] = v
+	}
+	// the file should not be encoded, or the ranges will not match the file.
+	headers["Accept-Encoding"] = []string{"identity"}
+	opt.Headers = headers
+
+	return opt
+}
+
+// transfer downloads the incomplete segments in parallel, and saves the state periodically and
+// after all the segments are done or any segment failed.
+func (d *download) transfer(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	defer d.saveState()
+
+	if d.opt.OnProgress != nil {
+		written := int64(0)
+		for _, segment := range d.state.Segments {
+			written += segment.Written
+		}
+		d.tracker = newProgressTracker(d.opt.OnProgress, d.state.Size, written)
+	}
+
+	stop, stopped := make(chan struct{}), make(chan struct{})
+	go d.saveStatePeriodically(stop, stopped)
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(d.state.Segments))
+	for i, segment := range d.state.Segments {
+		if segment.End >= 0 && segment.Start+segment.Written > segment.End {
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, segment *downloadSegment) {
+			defer wg.Done()
+
+			errs[i] = d.transferSegment(ctx, segment)
+			if errs[i] != nil {
+				cancel()
+			}
+		}(i, segment)
+	}
+	wg.Wait()
+	close(stop)
+	<-stopped
+	if d.tracker != nil {
+		d.tracker.finish()
+	}
+
+	// return the error that causes the cancellation first.
+	var canceledErr error
+	for _, err := range errs {
+		if err == nil {
+			continue
+		} else if !errors.Is(err, context.Canceled) || d.context().Err() != nil {
+			return err
+		}
+		canceledErr = err
+	}
+
+	return canceledErr
+}
+
+// saveStatePeriodically saves the state of the download until the stop channel is closed, so the
+// download can be resumed even if the process is killed during the transfer.
+func (d *download) saveStatePeriodically(stop <-chan struct{}, stopped chan<- struct{}) {
+	defer close(stopped)
+
+	ticker := time.NewTicker(downloadStateInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			d.saveState()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// transferSegment downloads the rest of the segment, and writes the data into the temporary file.
+func (d *download) transferSegment(ctx context.Context, segment *downloadSegment) error {
+	opt := d.requestOptions()
+	ctx, canFunc := d.cli.withTimeout(ctx, opt)
+	defer canFunc()
+	opt.Context = ctx
+
+	d.mutex.Lock()
+	offset := segment.Start + segment.Written
+	d.mutex.Unlock()
+
+	ranged := offset > 0 || len(d.state.Segments) > 1
+	if ranged {
+		if segment.End >= 0 {
+			opt.Headers["Range"] = []string{fmt.Sprintf("bytes=%d-%d", offset, segment.End)}
+		} else {
+			opt.Headers["Range"] = []string{fmt.Sprintf("bytes=%d-", offset)}
+		}
+		if etag := d.state.ETag; etag != "" && !strings.HasPrefix(etag, "W/") {
+			opt.Headers["If-Range"] = []string{etag}
+		} else if d.state.LastModified != "" {
+			opt.Headers["If-Range"] = []string{d.state.LastModified}
+		}
+	}
+
+	resp, err := d.cli.request(http.MethodGet, d.url, opt)
+	if err != nil {
+		closeResponseBody(resp)
+		var respErr *ResponseError
+		if errors.As(err, &respErr) &&
+			respErr.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+			return ErrFileChanged
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case ranged && resp.StatusCode == http.StatusPartialContent:
+		contentRange := resp.Header.Get("Content-Range")
+		if !strings.HasPrefix(contentRange, "bytes "+strconv.FormatInt(offset, 10)+"-") {
+			return ErrFileChanged
+		}
+	case ranged && len(d.state.Segments) > 1:
+		// the file has been changed, or the server ignores the range.
+		return ErrFileChanged
+	default:
+		// download the whole file by the response.
+		if err := d.file.Truncate(0); err != nil {
+			return err
+		}
+
+		d.mutex.Lock()
+		d.state.ETag = ""
+		d.state.LastModified = ""
+		d.setValidators(resp)
+		d.state.Size = -1
+		segment.End = -1
+		if resp.ContentLength >= 0 {
+			d.state.Size = resp.ContentLength
+			segment.End = resp.ContentLength - 1
+		}
+		segment.Written = 0
+		d.mutex.Unlock()
+		offset = 0
+
+		if d.tracker != nil {
+			d.tracker.reset(d.state.Size, 0)
+		}
+	}
+
+	body := io.Reader(resp.Body)
+	if segment.End >= 0 {
+		body = io.LimitReader(body, segment.End-offset+1)
+	}
+
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := body.Read(buf)
+		if n > 0 {
+			if _, err := d.file.WriteAt(buf[:n], offset); err != nil {
+				return err
+			}
+			offset += int64(n)
+
+			d.mutex.Lock()
+			segment.Written += int64(n)
+			d.mutex.Unlock()
+			if d.tracker != nil {
+				d.tracker.add(int64(n))
+			}
+		}
+
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+	}
+
+	if segment.End >= 0 && offset <= segment.End {
+		return io.ErrUnexpectedEOF
+	}
+	if segment.End < 0 {
+		d.mutex.Lock()
+		d.state.Size = offset
+		segment.End = offset - 1
+		d.mutex.Unlock()
+	}
+
+	return nil
+}
+
+// setValidators sets the validators of the file by the response.
+func (d *download) setValidators(resp *http.Response) {
+	d.state.ETag = resp.Header.Get("ETag")
+	d.state.LastModified = resp.Header.Get("Last-Modified")
+}
+
+// finish verifies the checksum of the downloaded file, and renames the temporary file to the path.
+func (d *download) finish() (int64, error) {
+	if err := d.file.Close(); err != nil {
+		return 0, err
+	}
+	d.file = nil
+
+	if d.opt.Checksum != "" {
+		if err := d.verify(); err != nil {
+			d.clean()
+			return 0, err
+		}
+	}
+
+	if err := os.Rename(d.tempPath(), d.path); err != nil {
+		return 0, err
+	}
+	os.Remove(d.statePath())
+
+	return d.state.Size, nil
+}
+
+// verify checks whether the checksum of the temporary file equals the expected checksum.
+func (d *download) verify() error {
+	file, err := os.Open(d.tempPath())
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	newHash := d.opt.Hash
+	if newHash == nil {
+		newHash = sha256.New
+	}
+	h := newHash()
+	if _, err := io.Copy(h, file); err != nil {
+		return err
+	}
+
+	checksum := hex.EncodeToString(h.Sum(nil))
+	if !strings.EqualFold(checksum, d.opt.Checksum) {
+		return fmt.Errorf("%w: expected %s, got %s", ErrChecksumMismatch, d.opt.Checksum, checksum)
+	}
+
+	return nil
+}
+
+// close closes the temporary file.
+func (d *download) close() {
+	if d.file != nil {
+		d.file.Close()
+		d.file = nil
+	}
+}
+
+// clean removes the temporary file and the state file, and resets the state of the download.
+func (d *download) clean() {
+	d.close()
+	os.Remove(d.tempPath())
+	os.Remove(d.statePath())
+	d.state = nil
+}
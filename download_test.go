@@ -0,0 +1,311 @@
+package request
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ghosind/go-assert"
+)
+
+func getDownloadContent(size int) []byte {
+	content := make([]byte, size)
+	for i := range content {
+		content[i] = byte(i % 251)
+	}
+	return content
+}
+
+// newDownloadTestClient creates a client that records the status codes and the Range header of
+// the GET requests.
+func newDownloadTestClient() (*Client, func() []string) {
+	cli := New()
+	records := make([]string, 0)
+	mutex := sync.Mutex{}
+
+	cli.UseResponseInterceptor(func(resp *http.Response) error {
+		if resp.Request.Method != http.MethodGet {
+			return nil
+		}
+		mutex.Lock()
+		records = append(records, resp.Status[:3]+" "+resp.Request.Header.Get("Range"))
+		mutex.Unlock()
+		return nil
+	})
+
+	return cli, func() []string {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return records
+	}
+}
+
+func TestDownload(t *testing.T) {
+	a := assert.New(t)
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "file")
+
+	progresses := make([]Progress, 0)
+	n, err := Download("http://127.0.0.1:8080/download?size=100000", path, DownloadOptions{
+		OnProgress: func(progress Progress) {
+			progresses = append(progresses, progress)
+		},
+	})
+	a.NilNow(err)
+	a.EqualNow(n, int64(100000))
+
+	data, err := os.ReadFile(path)
+	a.NilNow(err)
+	a.TrueNow(bytes.Equal(data, getDownloadContent(100000)))
+
+	a.TrueNow(len(progresses) > 0)
+	last := progresses[len(progresses)-1]
+	a.EqualNow(last.Transferred, int64(100000))
+	a.EqualNow(last.Total, int64(100000))
+	a.TrueNow(last.Rate > 0)
+
+	entries, err := os.ReadDir(dir)
+	a.NilNow(err)
+	a.EqualNow(len(entries), 1)
+}
+
+func TestDownloadWithSegments(t *testing.T) {
+	a := assert.New(t)
+
+	cli, records := newDownloadTestClient()
+	path := filepath.Join(t.TempDir(), "file")
+
+	n, err := cli.Download("http://127.0.0.1:8080/download?size=10000", path, DownloadOptions{
+		Segments: 4,
+	})
+	a.NilNow(err)
+	a.EqualNow(n, int64(10000))
+	a.EqualNow(len(records()), 4)
+	for _, record := range records() {
+		a.EqualNow(record[:3], "206")
+	}
+
+	data, err := os.ReadFile(path)
+	a.NilNow(err)
+	a.TrueNow(bytes.Equal(data, getDownloadContent(10000)))
+
+	// download by a single request if the server doesn't support the range requests
+	cli, records = newDownloadTestClient()
+	n, err = cli.Download("http://127.0.0.1:8080/download?size=10000&noRange=1", path,
+		DownloadOptions{Segments: 4})
+	a.NilNow(err)
+	a.EqualNow(n, int64(10000))
+	a.EqualNow(records(), []string{"200 "})
+}
+
+func TestDownloadResume(t *testing.T) {
+	a := assert.New(t)
+
+	cli, records := newDownloadTestClient()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "file")
+	url := "http://127.0.0.1:8080/download?key=download-resume&size=10000&failAt=3000"
+
+	_, err := cli.Download(url, path)
+	a.NotNilNow(err)
+	_, err = os.Stat(path + ".download")
+	a.NilNow(err)
+	_, err = os.Stat(path + ".download.json")
+	a.NilNow(err)
+
+	n, err := cli.Download(url, path)
+	a.NilNow(err)
+	a.EqualNow(n, int64(10000))
+	a.EqualNow(records(), []string{"200 ", "206 bytes=3000-9999"})
+
+	data, err := os.ReadFile(path)
+	a.NilNow(err)
+	a.TrueNow(bytes.Equal(data, getDownloadContent(10000)))
+
+	entries, err := os.ReadDir(dir)
+	a.NilNow(err)
+	a.EqualNow(len(entries), 1)
+}
+
+func TestDownloadWithWeakETag(t *testing.T) {
+	a := assert.New(t)
+
+	cli, records := newDownloadTestClient()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "file")
+	url := "http://127.0.0.1:8080/download?key=download-weak-etag&size=10000&weakETag=1"
+
+	// the temporary file is removed because the download can't be resumed
+	_, err := cli.Download(url+"&failAt=3000", path)
+	a.NotNilNow(err)
+	entries, err := os.ReadDir(dir)
+	a.NilNow(err)
+	a.EqualNow(len(entries), 0)
+
+	// the file is not split into the segments without the validator
+	n, err := cli.Download(url, path, DownloadOptions{Segments: 4})
+	a.NilNow(err)
+	a.EqualNow(n, int64(10000))
+	a.EqualNow(records(), []string{"200 ", "200 "})
+
+	data, err := os.ReadFile(path)
+	a.NilNow(err)
+	a.TrueNow(bytes.Equal(data, getDownloadContent(10000)))
+}
+
+func TestDownloadSaveStatePeriodically(t *testing.T) {
+	a := assert.New(t)
+
+	cli, records := newDownloadTestClient()
+	path := filepath.Join(t.TempDir(), "file")
+	url := "http://127.0.0.1:8080/download?key=download-save-state&size=10000&stallAt=3000"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := cli.Download(url, path, DownloadOptions{
+			RequestOptions: RequestOptions{Context: ctx},
+		})
+		errCh <- err
+	}()
+
+	// the state is saved while the transfer is stalled
+	state := new(downloadState)
+	for start := time.Now(); time.Since(start) < 3*time.Second; {
+		time.Sleep(50 * time.Millisecond)
+		if data, err := os.ReadFile(path + ".download.json"); err == nil {
+			a.NilNow(json.Unmarshal(data, state))
+			break
+		}
+	}
+	a.EqualNow(len(state.Segments), 1)
+	a.EqualNow(state.Segments[0].Written, int64(3000))
+
+	cancel()
+	a.NotNilNow(<-errCh)
+
+	n, err := cli.Download(url, path)
+	a.NilNow(err)
+	a.EqualNow(n, int64(10000))
+	a.EqualNow(records(), []string{"200 ", "206 bytes=3000-9999"})
+}
+
+func TestDownloadResumeWithSegments(t *testing.T) {
+	a := assert.New(t)
+
+	cli, records := newDownloadTestClient()
+	path := filepath.Join(t.TempDir(), "file")
+	url := "http://127.0.0.1:8080/download?key=download-resume-segments&size=10000&failAt=1000"
+
+	_, err := cli.Download(url, path, DownloadOptions{Segments: 2})
+	a.NotNilNow(err)
+	sent := len(records())
+
+	n, err := cli.Download(url, path, DownloadOptions{Segments: 2})
+	a.NilNow(err)
+	a.EqualNow(n, int64(10000))
+
+	// the failed segment resumes from the failed offset
+	resumed := false
+	for _, record := range records()[sent:] {
+		if record == "206 bytes=1000-4999" || record == "206 bytes=6000-9999" {
+			resumed = true
+		}
+	}
+	a.TrueNow(resumed)
+
+	data, err := os.ReadFile(path)
+	a.NilNow(err)
+	a.TrueNow(bytes.Equal(data, getDownloadContent(10000)))
+}
+
+func TestDownloadWithChangedFile(t *testing.T) {
+	a := assert.New(t)
+
+	cli, records := newDownloadTestClient()
+	path := filepath.Join(t.TempDir(), "file")
+	url := "http://127.0.0.1:8080/download?key=download-changed&size=10000&failAt=3000&changed=1"
+
+	_, err := cli.Download(url, path)
+	a.NotNilNow(err)
+
+	// restart from the beginning because the ETag is changed
+	n, err := cli.Download(url, path)
+	a.NilNow(err)
+	a.EqualNow(n, int64(10000))
+	a.EqualNow(records(), []string{"200 ", "200 bytes=3000-9999"})
+
+	data, err := os.ReadFile(path)
+	a.NilNow(err)
+	a.TrueNow(bytes.Equal(data, getDownloadContent(10000)))
+
+	// the segments can't be combined if the file is always changed
+	_, err = cli.Download("http://127.0.0.1:8080/download?key=download-changed-segments&changed=1",
+		filepath.Join(t.TempDir(), "file"), DownloadOptions{Segments: 2})
+	a.TrueNow(errors.Is(err, ErrFileChanged))
+}
+
+func TestDownloadWithChecksum(t *testing.T) {
+	a := assert.New(t)
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "file")
+
+	sum := sha256.Sum256(getDownloadContent(1024))
+	_, err := Download("http://127.0.0.1:8080/download", path, DownloadOptions{
+		Checksum: hex.EncodeToString(sum[:]),
+	})
+	a.NilNow(err)
+
+	md5Sum := md5.Sum(getDownloadContent(1024))
+	_, err = Download("http://127.0.0.1:8080/download", path, DownloadOptions{
+		Checksum: hex.EncodeToString(md5Sum[:]),
+		Hash:     md5.New,
+	})
+	a.NilNow(err)
+
+	path = filepath.Join(dir, "invalid")
+	_, err = Download("http://127.0.0.1:8080/download", path, DownloadOptions{
+		Checksum: hex.EncodeToString(md5Sum[:]),
+	})
+	a.TrueNow(errors.Is(err, ErrChecksumMismatch))
+
+	entries, err := os.ReadDir(dir)
+	a.NilNow(err)
+	a.EqualNow(len(entries), 1)
+}
+
+func TestDownloadWithFailedResponse(t *testing.T) {
+	a := assert.New(t)
+
+	cli := New(Config{
+		Bulkhead: &BulkheadConfig{MaxConcurrent: 1, MaxQueue: -1},
+	})
+	path := filepath.Join(t.TempDir(), "file")
+
+	// the responses are closed to release the slot of the bulkhead
+	for _, segments := range []int{1, 2} {
+		_, err := cli.Download("http://127.0.0.1:8080/status?status=500", path, DownloadOptions{
+			Segments: segments,
+		})
+		var respErr *ResponseError
+		a.TrueNow(errors.As(err, &respErr))
+		a.EqualNow(cli.BulkheadStats().InFlight, int64(0))
+	}
+
+	n, err := cli.Download("http://127.0.0.1:8080/download", path)
+	a.NilNow(err)
+	a.EqualNow(n, int64(1024))
+}
@@ -0,0 +1,293 @@
+package request
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Decompressor creates a reader that decompresses the content read from the reader, and it's used
+// to decode the response body by the content encoding. The gzip, deflate, br, and zstd encodings
+// are built in, and other encodings can be supported by registering the decompressors.
+//
+//	request.RegisterDecompressor("lz4", func(r io.Reader) (io.ReadCloser, error) {
+//	  return io.NopCloser(lz4.NewReader(r)), nil
+//	})
+type Decompressor func(r io.Reader) (io.ReadCloser, error)
+
+// decompressorRegistry is a collection of the decompressors with their content encodings.
+type decompressorRegistry struct {
+	// decompressors are the registered decompressors with their content encodings.
+	decompressors map[string]Decompressor
+	// encodings are the registered content encodings in the order of registration.
+	encodings []string
+	// mutex is the locker for the registry.
+	mutex sync.RWMutex
+}
+
+// defaultDecompressors is the package-level decompressor registry, and it contains the built-in
+// decompressors.
+var defaultDecompressors *decompressorRegistry
+
+func init() {
+	defaultDecompressors = new(decompressorRegistry)
+	defaultDecompressors.register("gzip", newGzipReader)
+	defaultDecompressors.register("deflate", newDeflateReader)
+	defaultDecompressors.register("br", newBrotliReader)
+	defaultDecompressors.register("zstd", newZstdReader)
+}
+
+// RegisterDecompressor registers the decompressor for the content encoding to the package-level
+// registry, and all clients can decode the response bodies with the content encoding. The content
+// encoding will also be added into the `Accept-Encoding` field of the requests. It'll overwrite
+// the decompressor for the same content encoding.
+func RegisterDecompressor(encoding string, decompressor Decompressor) {
+	defaultDecompressors.register(encoding, decompressor)
+}
+
+// RegisterDecompressor registers the decompressor for the content encoding to the client, and it's
+// only available for the requests sent by this client. The decompressors registered to the client
+// take precedence over the package-level decompressors for the same content encoding.
+func (cli *Client) RegisterDecompressor(encoding string, decompressor Decompressor) {
+	cli.decompressors.register(encoding, decompressor)
+}
+
+// getDecompressor gets the decompressor for the content encoding from the client's decompressors
+// or the package-level decompressors.
+func (cli *Client) getDecompressor(encoding string) (Decompressor, bool) {
+	if cli != nil {
+		if decompressor, ok := cli.decompressors.get(encoding); ok {
+			return decompressor, true
+		}
+	}
+
+	return defaultDecompressors.get(encoding)
+}
+
+// getAcceptEncoding returns the value of the `Accept-Encoding` field with the content encodings
+// that can be decoded, for example, "gzip, deflate, br, zstd".
+func (cli *Client) getAcceptEncoding() string {
+	encodings := defaultDecompressors.list()
+	if cli != nil {
+		for _, encoding := range cli.decompressors.list() {
+			if _, ok := defaultDecompressors.get(encoding); !ok {
+				encodings = append(encodings, encoding)
+			}
+		}
+	}
+
+	return strings.Join(encodings, ", ")
+}
+
+// setAcceptEncoding sets the `Accept-Encoding` field by the supported content encodings if it's
+// not set and the decompression is not disabled.
+func (cli *Client) setAcceptEncoding(req *http.Request, opt RequestOptions) {
+	if opt.DisableDecompress || req.Header.Get("Accept-Encoding") != "" {
+		return
+	}
+
+	req.Header.Set("Accept-Encoding", cli.getAcceptEncoding())
+}
+
+// decodeResponseBody tries to get the encodings of the response's content, and decode
+// (decompress) it by the decompressors of the encodings in the reverse order of applying. The body
+// is decoded while reading without buffering, and reading the body fails with the error if it
+// can't be decoded. The body will not be decoded if any encoding is unsupported.
+func (cli *Client) decodeResponseBody(resp *http.Response) *http.Response {
+	encodings := getContentEncodings(resp.Header)
+	if len(encodings) == 0 || resp.Body == nil || resp.Body == http.NoBody {
+		return resp
+	}
+
+	decompressors := make([]Decompressor, 0, len(encodings))
+	for _, encoding := range encodings {
+		decompressor, ok := cli.getDecompressor(encoding)
+		if !ok {
+			return resp
+		}
+		decompressors = append(decompressors, decompressor)
+	}
+
+	body := &decodedBody{Reader: resp.Body, closers: []io.Closer{resp.Body}}
+	for i := len(decompressors) - 1; i >= 0; i-- {
+		reader := &lazyReader{source: body.Reader, decompressor: decompressors[i]}
+		body.Reader = reader
+		body.closers = append(body.closers, reader)
+	}
+	resp.Body = body
+
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+
+	return resp
+}
+
+// getContentEncodings returns the content encodings in the `Content-Encoding` fields in the order
+// that they were applied, and the "identity" encoding is ignored.
+func getContentEncodings(header http.Header) []string {
+	encodings := make([]string, 0, 1)
+	for _, value := range header.Values("Content-Encoding") {
+		for _, encoding := range strings.Split(value, ",") {
+			encoding = strings.ToLower(strings.TrimSpace(encoding))
+			switch encoding {
+			case "", "identity":
+				continue
+			case "x-gzip":
+				encoding = "gzip"
+			}
+			encodings = append(encodings, encoding)
+		}
+	}
+
+	return encodings
+}
+
+// register adds the decompressor for the content encoding into the registry.
+func (registry *decompressorRegistry) register(encoding string, decompressor Decompressor) {
+	if decompressor == nil {
+		return
+	}
+
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	if registry.decompressors == nil {
+		registry.decompressors = make(map[string]Decompressor)
+	}
+
+	encoding = strings.ToLower(encoding)
+	if _, ok := registry.decompressors[encoding]; !ok {
+		registry.encodings = append(registry.encodings, encoding)
+	}
+	registry.decompressors[encoding] = decompressor
+}
+
+// get gets the decompressor for the content encoding.
+func (registry *decompressorRegistry) get(encoding string) (Decompressor, bool) {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+
+	decompressor, ok := registry.decompressors[encoding]
+	return decompressor, ok
+}
+
+// list returns the registered content encodings.
+func (registry *decompressorRegistry) list() []string {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+
+	encodings := make([]string, len(registry.encodings))
+	copy(encodings, registry.encodings)
+	return encodings
+}
+
+// decodedBody is the response body that reads the decoded content, and it closes the
+// decompressors and the original body when it's closed.
+type decodedBody struct {
+	io.Reader
+	closers []io.Closer
+}
+
+// Close closes the decompressors and the original body, and returns the error of closing the
+// original body.
+func (body *decodedBody) Close() error {
+	for i := len(body.closers) - 1; i > 0; i-- {
+		body.closers[i].Close()
+	}
+
+	return body.closers[0].Close()
+}
+
+// lazyReader creates the decompressor when it's read for the first time, so the response can be
+// returned before the header of the encoded content is received, and the error of creating the
+// decompressor is returned by reading.
+type lazyReader struct {
+	source       io.Reader
+	decompressor Decompressor
+	reader       io.ReadCloser
+	err          error
+}
+
+// Read creates the decompressor on the first call, and reads the decompressed content from it.
+func (reader *lazyReader) Read(p []byte) (int, error) {
+	if reader.reader == nil && reader.err == nil {
+		decompressed, err := reader.decompressor(reader.source)
+		if err != nil {
+			reader.err = err
+		} else {
+			reader.reader = decompressed
+		}
+	}
+	if reader.err != nil {
+		return 0, reader.err
+	}
+
+	return reader.reader.Read(p)
+}
+
+// Close closes the decompressor if it has been created.
+func (reader *lazyReader) Close() error {
+	if reader.reader == nil {
+		return nil
+	}
+
+	return reader.reader.Close()
+}
+
+// newGzipReader creates a reader to decompress the gzip content.
+func newGzipReader(r io.Reader) (io.ReadCloser, error) {
+	reader, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return reader, nil
+}
+
+// newDeflateReader creates a reader to decompress the deflate content. The content should be in
+// the zlib format, but some servers send the raw deflate data, so the format will be detected by
+// the header.
+func newDeflateReader(r io.Reader) (io.ReadCloser, error) {
+	reader := bufio.NewReader(r)
+	header, err := reader.Peek(2)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	if len(header) == 2 && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(reader)
+	}
+
+	return flate.NewReader(reader), nil
+}
+
+// newBrotliReader creates a reader to decompress the brotli content.
+func newBrotliReader(r io.Reader) (io.ReadCloser, error) {
+	return io.NopCloser(brotli.NewReader(r)), nil
+}
+
+// newZstdReader creates a reader to decompress the zstd content. The decoder runs in the calling
+// goroutine, and its memory is limited to the maximum window size of the HTTP content coding
+// (RFC 8878).
+func newZstdReader(r io.Reader) (io.ReadCloser, error) {
+	decoder, err := zstd.NewReader(
+		r,
+		zstd.WithDecoderConcurrency(1),
+		zstd.WithDecoderMaxWindow(8<<20),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return decoder.IOReadCloser(), nil
+}
@@ -0,0 +1,155 @@
+package request
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ghosind/go-assert"
+)
+
+func TestDecodeResponseBody(t *testing.T) {
+	a := assert.New(t)
+
+	for _, encoding := range []string{
+		"br", "gzip", "deflate", "zstd", "gzip,br", "deflate,gzip,br", "zstd,br",
+	} {
+		data, resp, err := ToObject[testResponse](GET("http://127.0.0.1:8080/test", RequestOptions{
+			Parameters: map[string][]string{"encoding": {encoding}},
+		}))
+		a.NilNow(err)
+		a.EqualNow(*data.Path, "/test")
+		a.EqualNow(resp.Header.Get("Content-Encoding"), "")
+		a.EqualNow(resp.ContentLength, int64(-1))
+	}
+
+	// deflate in the zlib format
+	data, _, err := ToObject[testResponse](GET("http://127.0.0.1:8080/test", RequestOptions{
+		Parameters: map[string][]string{
+			"encoding":        {"zlib"},
+			"contentEncoding": {"deflate"},
+		},
+	}))
+	a.NilNow(err)
+	a.EqualNow(*data.Path, "/test")
+}
+
+func TestDecodeResponseBodyWithUnsupportedEncoding(t *testing.T) {
+	a := assert.New(t)
+
+	resp, err := GET("http://127.0.0.1:8080/test", RequestOptions{
+		Parameters: map[string][]string{
+			"encoding":        {"gzip"},
+			"contentEncoding": {"gzip, compress"},
+		},
+	})
+	a.NilNow(err)
+	defer resp.Body.Close()
+	a.EqualNow(resp.Header.Get("Content-Encoding"), "gzip, compress")
+
+	// the body is still encoded
+	reader, err := gzip.NewReader(resp.Body)
+	a.NilNow(err)
+	content, err := io.ReadAll(reader)
+	a.NilNow(err)
+	a.TrueNow(strings.Contains(string(content), `"path":"/test"`))
+}
+
+func TestRegisterDecompressor(t *testing.T) {
+	a := assert.New(t)
+
+	// a custom encoding that is the gzip encoding with another name
+	custom := func(r io.Reader) (io.ReadCloser, error) {
+		return gzip.NewReader(r)
+	}
+	opt := RequestOptions{
+		Parameters: map[string][]string{
+			"encoding":        {"gzip"},
+			"contentEncoding": {"custom"},
+		},
+	}
+
+	cli := New(Config{
+		Decompressors: map[string]Decompressor{"custom": custom},
+	})
+	data, _, err := ToObject[testResponse](cli.GET("http://127.0.0.1:8080/test", opt))
+	a.NilNow(err)
+	a.EqualNow((*data.Headers)["Accept-Encoding"], []string{"gzip, deflate, br, zstd, custom"})
+
+	cli = New()
+	cli.RegisterDecompressor("CUSTOM", custom)
+	data, _, err = ToObject[testResponse](cli.GET("http://127.0.0.1:8080/test", opt))
+	a.NilNow(err)
+	a.EqualNow(*data.Path, "/test")
+
+	// not available for other clients
+	_, _, err = ToObject[testResponse](GET("http://127.0.0.1:8080/test", opt))
+	a.NotNilNow(err)
+}
+
+func TestAcceptEncoding(t *testing.T) {
+	a := assert.New(t)
+
+	data, _, err := ToObject[testResponse](GET("http://127.0.0.1:8080/test"))
+	a.NilNow(err)
+	a.EqualNow((*data.Headers)["Accept-Encoding"], []string{"gzip, deflate, br, zstd"})
+
+	// keep the field set by the user
+	data, _, err = ToObject[testResponse](GET("http://127.0.0.1:8080/test", RequestOptions{
+		Headers: map[string][]string{"accept-encoding": {"identity"}},
+	}))
+	a.NilNow(err)
+	a.EqualNow((*data.Headers)["Accept-Encoding"], []string{"identity"})
+
+	data, _, err = ToObject[testResponse](GET("http://127.0.0.1:8080/test", RequestOptions{
+		DisableDecompress: true,
+	}))
+	a.NilNow(err)
+	a.NotEqualNow((*data.Headers)["Accept-Encoding"], []string{"gzip, deflate, br, zstd"})
+}
+
+func TestDecodeDeflateStreaming(t *testing.T) {
+	a := assert.New(t)
+
+	pr, pw := io.Pipe()
+	defer pw.Close()
+
+	resp := &http.Response{
+		Header: http.Header{"Content-Encoding": {"deflate"}},
+		Body:   pr,
+	}
+	resp = New().decodeResponseBody(resp)
+
+	go func() {
+		writer, _ := flate.NewWriter(pw, -1)
+		writer.Write([]byte("Hello "))
+		writer.Flush()
+	}()
+
+	// the data can be read before the whole body is received
+	buf := make([]byte, 6)
+	_, err := io.ReadFull(resp.Body, buf)
+	a.NilNow(err)
+	a.EqualNow(string(buf), "Hello ")
+	a.NilNow(resp.Body.Close())
+
+	// the original body is closed
+	_, err = pw.Write([]byte{0})
+	a.EqualNow(err, io.ErrClosedPipe)
+}
+
+func TestDecodeResponseBodyWithInvalidData(t *testing.T) {
+	a := assert.New(t)
+
+	resp := New().decodeResponseBody(&http.Response{
+		Header: http.Header{"Content-Encoding": {"gzip"}},
+		Body:   io.NopCloser(bytes.NewReader([]byte("not the gzip content"))),
+	})
+	_, err := io.ReadAll(resp.Body)
+	a.EqualNow(err, gzip.ErrHeader)
+	a.NilNow(resp.Body.Close())
+}
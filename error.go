@@ -1,8 +1,39 @@
 package request
 
-import "errors"
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+)
 
 var (
+	// ErrBodyNotReplayable throws when a request needs to be re-sent but its body can't be read
+	// again, for example, the body of a multipart form that streams the parts.
+	ErrBodyNotReplayable error = errors.New("request body is not replayable")
+
+	// ErrBulkheadFull throws when no slot of the bulkhead is available for the request and the
+	// queue of the bulkhead is full.
+	ErrBulkheadFull error = errors.New("bulkhead is full")
+
+	// ErrChecksumMismatch throws when the checksum of the downloaded file is different from the
+	// expected checksum.
+	ErrChecksumMismatch error = errors.New("checksum mismatch")
+
+	// ErrCircuitOpen throws when the circuit breaker of the request's host is open, and the request
+	// is rejected without sending.
+	ErrCircuitOpen error = errors.New("circuit breaker is open")
+
+	// ErrFileChanged throws when the file has been changed on the server during the download, so
+	// the downloaded parts of the file can't be combined.
+	ErrFileChanged error = errors.New("file has been changed during the download")
+
+	// ErrInvalidCertificate throws when the certificates or the keys in the TLS config are invalid.
+	ErrInvalidCertificate error = errors.New("invalid certificate")
+
 	// ErrInvalidMethod throws when the method of the request is not a valid value.
 	ErrInvalidMethod error = errors.New("invalid HTTP method")
 
@@ -12,6 +43,152 @@ var (
 	// ErrNoURL throws when no uri and base url set in the request.
 	ErrNoURL error = errors.New("no url")
 
+	// ErrPublicKeyNotPinned throws when no certificate of the server matches the pinned public
+	// keys.
+	ErrPublicKeyNotPinned error = errors.New("no certificate matches the pinned public keys")
+
+	// ErrRateLimited throws when the request can't get the tokens of the rate limiter before the
+	// deadline of the request context.
+	ErrRateLimited error = errors.New("rate limit exceeded")
+
 	// ErrUnsupportedType throws when the content type is unsupported.
 	ErrUnsupportedType error = errors.New("unsupported content type")
 )
+
+// responseErrorBodyLimit is the maximum number of bytes of the response body that will be kept in
+// the response error.
+const responseErrorBodyLimit = 1024
+
+// ResponseError is the error for the responses that fail to pass the status code validation, and
+// it can be checked by `errors.As`.
+//
+//	resp, err := request.GET("http://example.com")
+//	var respErr *request.ResponseError
+//	if errors.As(err, &respErr) {
+//	  log.Printf("%s %s: %d %s", respErr.Method, respErr.URL, respErr.StatusCode, respErr.Body)
+//	}
+type ResponseError struct {
+	// StatusCode is the status code of the response.
+	StatusCode int
+	// Status is the status of the response, for example, "404 Not Found".
+	Status string
+	// Method is the HTTP method of the request.
+	Method string
+	// URL is the URL of the request.
+	URL string
+	// Header is the headers of the response.
+	Header http.Header
+	// Body is the first bytes (up to 1KB) of the response body. The body of the response is still
+	// readable with the whole content.
+	Body []byte
+}
+
+// Error returns the message of the error.
+func (err *ResponseError) Error() string {
+	return fmt.Sprintf("request failed with status code %d", err.StatusCode)
+}
+
+// newResponseError creates a new ResponseError by the response. It reads an excerpt of the
+// response body, and then restores the body of the response to keep it readable.
+func newResponseError(resp *http.Response) *ResponseError {
+	err := new(ResponseError)
+
+	err.StatusCode = resp.StatusCode
+	err.Status = resp.Status
+	err.Header = resp.Header
+	if resp.Request != nil {
+		err.Method = resp.Request.Method
+		if resp.Request.URL != nil {
+			err.URL = resp.Request.URL.String()
+		}
+	}
+
+	if resp.Body != nil {
+		body := resp.Body
+		excerpt, _ := io.ReadAll(io.LimitReader(body, responseErrorBodyLimit))
+		err.Body = excerpt
+
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{
+			Reader: io.MultiReader(bytes.NewReader(excerpt), body),
+			Closer: body,
+		}
+	}
+
+	return err
+}
+
+// BodyTooLargeError is the error for reading a response body that is larger than the maximum body
+// size, and it can be checked by `errors.As`.
+//
+//	_, _, err := request.ToString(request.GET("http://example.com", request.RequestOptions{
+//	  MaxBodySize: 1 << 20,
+//	}))
+//	var sizeErr *request.BodyTooLargeError
+//	if errors.As(err, &sizeErr) {
+//	  log.Printf("the response body is larger than %d bytes", sizeErr.Limit)
+//	}
+type BodyTooLargeError struct {
+	// Limit is the maximum number of bytes of the response body.
+	Limit int64
+}
+
+// Error returns the message of the error.
+func (err *BodyTooLargeError) Error() string {
+	return fmt.Sprintf("response body exceeds the limit of %d bytes", err.Limit)
+}
+
+// LineError is the error for decoding a line of the newline-delimited JSON stream, and it can be
+// checked by `errors.As`.
+type LineError struct {
+	// Line is the line number that starts from 1.
+	Line int
+	// Err is the error of decoding the line.
+	Err error
+}
+
+// Error returns the message of the error.
+func (err *LineError) Error() string {
+	return fmt.Sprintf("line %d: %v", err.Line, err.Err)
+}
+
+// Unwrap returns the error of decoding the line.
+func (err *LineError) Unwrap() error {
+	return err.Err
+}
+
+// IsClientError returns true if the error is a ResponseError with a 4XX status code.
+func IsClientError(err error) bool {
+	var respErr *ResponseError
+	if !errors.As(err, &respErr) {
+		return false
+	}
+
+	return respErr.StatusCode >= http.StatusBadRequest &&
+		respErr.StatusCode < http.StatusInternalServerError
+}
+
+// IsServerError returns true if the error is a ResponseError with a 5XX status code.
+func IsServerError(err error) bool {
+	var respErr *ResponseError
+	if !errors.As(err, &respErr) {
+		return false
+	}
+
+	return respErr.StatusCode >= http.StatusInternalServerError && respErr.StatusCode < 600
+}
+
+// IsTimeout returns true if the error is caused by the request timing out, including the
+// deadline of the context being exceeded and the network timeout errors.
+func IsTimeout(err error) bool {
+	if err == nil {
+		return false
+	} else if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
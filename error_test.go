@@ -0,0 +1,78 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ghosind/go-assert"
+)
+
+func TestResponseError(t *testing.T) {
+	a := assert.New(t)
+
+	resp, err := GET("http://localhost:8080/status?status=404&message=not+found")
+	a.NotNilNow(err)
+	a.EqualNow(err.Error(), "request failed with status code 404")
+	a.TrueNow(IsClientError(err))
+	a.NotTrueNow(IsServerError(err))
+	a.NotTrueNow(IsTimeout(err))
+
+	var respErr *ResponseError
+	a.TrueNow(errors.As(err, &respErr))
+	a.EqualNow(respErr.StatusCode, http.StatusNotFound)
+	a.EqualNow(respErr.Status, "404 Not Found")
+	a.EqualNow(respErr.Method, http.MethodGet)
+	a.EqualNow(respErr.URL, "http://localhost:8080/status?message=not+found&status=404")
+	a.EqualNow(string(respErr.Body), "not found")
+	a.NotNilNow(respErr.Header)
+
+	// the response body is still readable
+	content, _, err := ToString(resp, nil)
+	a.NilNow(err)
+	a.EqualNow(content, "not found")
+
+	_, err = GET("http://localhost:8080/status?status=503")
+	a.NotNilNow(err)
+	a.NotTrueNow(IsClientError(err))
+	a.TrueNow(IsServerError(err))
+}
+
+func TestResponseErrorBodyLimit(t *testing.T) {
+	a := assert.New(t)
+
+	body := strings.Repeat("a", responseErrorBodyLimit*2)
+	resp := &http.Response{
+		StatusCode: http.StatusBadRequest,
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+
+	err := newResponseError(resp)
+	a.EqualNow(len(err.Body), responseErrorBodyLimit)
+	a.EqualNow(err.Method, "")
+
+	content, err2 := io.ReadAll(resp.Body)
+	a.NilNow(err2)
+	a.EqualNow(string(content), body)
+}
+
+func TestIsTimeout(t *testing.T) {
+	a := assert.New(t)
+
+	a.NotTrueNow(IsTimeout(nil))
+	a.NotTrueNow(IsTimeout(errors.New("test error")))
+	a.TrueNow(IsTimeout(context.DeadlineExceeded))
+
+	ctx, canFunc := context.WithTimeout(context.Background(), -1)
+	defer canFunc()
+	_, err := GET("http://localhost:8080", RequestOptions{
+		Context: ctx,
+	})
+	a.NotNilNow(err)
+	a.TrueNow(IsTimeout(err))
+	a.NotTrueNow(IsClientError(err))
+	a.NotTrueNow(IsServerError(err))
+}
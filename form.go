@@ -0,0 +1,272 @@
+package request
+
+import (
+	"encoding"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// formTagName is the struct tag name for the form-urlencoded encoder.
+const formTagName = "form"
+
+// encodeForm serializes the data into the `application/x-www-form-urlencoded` format. It
+// supports `url.Values`, `map[string][]string`, `map[string]string`, other maps with string keys,
+// and structs (or pointers to structs).
+//
+// The struct fields can be customized by the `form` tag, for example:
+//
+//	type Login struct {
+//	  Username string   `form:"username"`
+//	  Password string   `form:"password"`
+//	  Remember bool     `form:"remember,omitempty"`
+//	  Scopes   []string `form:"scope"`    // scope=a&scope=b
+//	  Profile  Profile  `form:"profile"`  // profile[name]=xxx
+//	  Ignored  string   `form:"-"`
+//	}
+func encodeForm(data any) ([]byte, error) {
+	values, err := toFormValues(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(values.Encode()), nil
+}
+
+// toFormValues converts the data into an `url.Values` object.
+func toFormValues(data any) (url.Values, error) {
+	switch v := data.(type) {
+	case url.Values:
+		return v, nil
+	case map[string][]string:
+		return url.Values(v), nil
+	case map[string]string:
+		values := make(url.Values, len(v))
+		for k, val := range v {
+			values.Set(k, val)
+		}
+		return values, nil
+	}
+
+	rv := reflect.ValueOf(data)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return url.Values{}, nil
+		}
+		rv = rv.Elem()
+	}
+
+	switch rv.Kind() {
+	case reflect.Map, reflect.Struct:
+		values := make(url.Values)
+		if err := encodeFormValue(values, "", rv); err != nil {
+			return nil, err
+		}
+		return values, nil
+	default:
+		return nil, ErrUnsupportedType
+	}
+}
+
+// encodeFormValue encodes the value with the key into the values. The nested struct fields and
+// map entries are named as `key[field]`, and the elements of slices or arrays are added with the
+// same key, or named as `key[index]` if the elements are structs or maps.
+func encodeFormValue(values url.Values, key string, rv reflect.Value) error {
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+
+	if s, ok, err := formatTextValue(rv); ok || err != nil {
+		if err != nil {
+			return err
+		}
+		values.Add(key, s)
+		return nil
+	}
+
+	switch rv.Kind() {
+	case reflect.Struct:
+		return encodeFormStruct(values, key, rv)
+	case reflect.Map:
+		return encodeFormMap(values, key, rv)
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8 {
+			values.Add(key, string(rv.Bytes()))
+			return nil
+		}
+
+		for i := 0; i < rv.Len(); i++ {
+			elem := rv.Index(i)
+			elemKey := key
+			if isFormContainer(elem) {
+				elemKey = formKey(key, strconv.Itoa(i))
+			}
+			if err := encodeFormValue(values, elemKey, elem); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	s, err := formatScalarValue(rv)
+	if err != nil {
+		return err
+	}
+	values.Add(key, s)
+
+	return nil
+}
+
+// encodeFormStruct encodes the exported fields of the struct by their `form` tags. The fields of
+// the embedded structs without tags will be promoted to the parent level.
+func encodeFormStruct(values url.Values, prefix string, rv reflect.Value) error {
+	rt := rv.Type()
+
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if !field.IsExported() && !field.Anonymous {
+			continue
+		}
+
+		tag := field.Tag.Get(formTagName)
+		if tag == "-" {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(tag, ",")
+		fv := rv.Field(i)
+
+		if strings.Contains(opts, "omitempty") && fv.IsZero() {
+			continue
+		}
+
+		if field.Anonymous && name == "" {
+			embedded := fv
+			for embedded.Kind() == reflect.Pointer {
+				if embedded.IsNil() {
+					break
+				}
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				if err := encodeFormStruct(values, prefix, embedded); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		if err := encodeFormValue(values, formKey(prefix, name), fv); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// encodeFormMap encodes the map entries in the order of their keys, and the map must have string
+// keys.
+func encodeFormMap(values url.Values, prefix string, rv reflect.Value) error {
+	if rv.Type().Key().Kind() != reflect.String {
+		return ErrUnsupportedType
+	}
+
+	keys := rv.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].String() < keys[j].String()
+	})
+
+	for _, k := range keys {
+		if err := encodeFormValue(values, formKey(prefix, k.String()), rv.MapIndex(k)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// formKey returns the name of the nested field, it'll return `prefix[name]` if the prefix is not
+// empty.
+func formKey(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+
+	return prefix + "[" + name + "]"
+}
+
+// isFormContainer checks whether the value is a struct or a map that needs to be encoded as nested
+// fields.
+func isFormContainer(rv reflect.Value) bool {
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return false
+		}
+		rv = rv.Elem()
+	}
+
+	if rv.CanInterface() {
+		if _, ok := rv.Interface().(encoding.TextMarshaler); ok {
+			return false
+		}
+	}
+
+	return rv.Kind() == reflect.Struct || rv.Kind() == reflect.Map
+}
+
+// formatTextValue formats the value by its `MarshalText` method if it implements the
+// `encoding.TextMarshaler` interface.
+func formatTextValue(rv reflect.Value) (string, bool, error) {
+	if !rv.CanInterface() {
+		return "", false, nil
+	}
+
+	marshaler, ok := rv.Interface().(encoding.TextMarshaler)
+	if !ok && rv.CanAddr() {
+		marshaler, ok = rv.Addr().Interface().(encoding.TextMarshaler)
+	}
+	if !ok {
+		return "", false, nil
+	}
+
+	text, err := marshaler.MarshalText()
+	if err != nil {
+		return "", false, err
+	}
+
+	return string(text), true, nil
+}
+
+// formatScalarValue formats the value of the basic types as a string.
+func formatScalarValue(rv reflect.Value) (string, error) {
+	switch rv.Kind() {
+	case reflect.String:
+		return rv.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(rv.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Uintptr:
+		return strconv.FormatUint(rv.Uint(), 10), nil
+	case reflect.Float32:
+		return strconv.FormatFloat(rv.Float(), 'f', -1, 32), nil
+	case reflect.Float64:
+		return strconv.FormatFloat(rv.Float(), 'f', -1, 64), nil
+	default:
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedType, rv.Kind())
+	}
+}
@@ -0,0 +1,107 @@
+package request
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/ghosind/go-assert"
+)
+
+func TestEncodeFormBasicTypes(t *testing.T) {
+	a := assert.New(t)
+
+	out, err := encodeForm(url.Values{"a": {"1", "2"}, "b": {"x y"}})
+	a.NilNow(err)
+	a.EqualNow(string(out), "a=1&a=2&b=x+y")
+
+	out, err = encodeForm(map[string][]string{"a": {"1"}})
+	a.NilNow(err)
+	a.EqualNow(string(out), "a=1")
+
+	out, err = encodeForm(map[string]string{"grant_type": "client_credentials", "scope": "a b"})
+	a.NilNow(err)
+	a.EqualNow(string(out), "grant_type=client_credentials&scope=a+b")
+
+	out, err = encodeForm(map[string]any{"n": 1, "f": 1.5, "ok": true})
+	a.NilNow(err)
+	a.EqualNow(string(out), "f=1.5&n=1&ok=true")
+
+	_, err = encodeForm(1)
+	a.NotNilNow(err)
+
+	_, err = encodeForm(map[int]string{1: "a"})
+	a.NotNilNow(err)
+}
+
+func TestEncodeFormStruct(t *testing.T) {
+	a := assert.New(t)
+
+	type testProfile struct {
+		Name string `form:"name"`
+		Age  int    `form:"age,omitempty"`
+	}
+	type testBase struct {
+		ID int `form:"id"`
+	}
+	type testForm struct {
+		testBase
+		Username string        `form:"username"`
+		Remember bool          `form:"remember,omitempty"`
+		Scopes   []string      `form:"scope"`
+		Profile  testProfile   `form:"profile"`
+		Friends  []testProfile `form:"friends"`
+		Extra    *testProfile  `form:"extra"`
+		Time     time.Time     `form:"time"`
+		Ignored  string        `form:"-"`
+		Default  string
+		private  string
+	}
+
+	out, err := encodeForm(&testForm{
+		testBase: testBase{ID: 1},
+		Username: "user",
+		Scopes:   []string{"read", "write"},
+		Profile:  testProfile{Name: "John"},
+		Friends:  []testProfile{{Name: "Jane", Age: 18}},
+		Time:     time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Ignored:  "ignored",
+		Default:  "default",
+		private:  "private",
+	})
+	a.NilNow(err)
+
+	values, err := url.ParseQuery(string(out))
+	a.NilNow(err)
+	a.EqualNow(values, url.Values{
+		"id":               {"1"},
+		"username":         {"user"},
+		"scope":            {"read", "write"},
+		"profile[name]":    {"John"},
+		"friends[0][name]": {"Jane"},
+		"friends[0][age]":  {"18"},
+		"time":             {"2024-01-02T03:04:05Z"},
+		"Default":          {"default"},
+	})
+
+	var nilForm *testForm
+	out, err = encodeForm(nilForm)
+	a.NilNow(err)
+	a.EqualNow(string(out), "")
+}
+
+func TestRequestWithFormBody(t *testing.T) {
+	a := assert.New(t)
+
+	data, _, err := ToObject[testResponse](POST("http://localhost:8080", RequestOptions{
+		ContentType: RequestContentTypeForm,
+		Body: map[string]string{
+			"grant_type": "client_credentials",
+		},
+	}))
+	a.NilNow(err)
+	a.NotNilNow(data.ContentType)
+	a.NotNilNow(data.Body)
+	a.EqualNow(*data.ContentType, "application/x-www-form-urlencoded")
+	a.EqualNow(*data.Body, "grant_type=client_credentials")
+}
@@ -2,4 +2,8 @@ module github.com/ghosind/go-request
 
 go 1.19
 
-require github.com/ghosind/go-assert v0.1.6
+require (
+	github.com/andybalholm/brotli v1.1.1
+	github.com/ghosind/go-assert v0.1.6
+	github.com/klauspost/compress v1.17.6
+)
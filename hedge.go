@@ -0,0 +1,178 @@
+package request
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// HedgePolicy defines how to send the hedged requests to reduce the tail latency. If an attempt
+// hasn't responded after the delay, another attempt will be sent without canceling the previous
+// ones, and the first successful response will be used. The other attempts will be canceled, and
+// their responses will be discarded.
+//
+// Only the requests with the safe methods (GET, HEAD, OPTIONS, and TRACE) and the replayable body
+// will be hedged. Every attempt follows the retry policy, the rate limits, and the other
+// settings, like a separate request.
+//
+//	cli := request.New(request.Config{
+//	  HedgePolicy: &request.HedgePolicy{
+//	    Delay:       50 * time.Millisecond,
+//	    MaxAttempts: 3,
+//	  },
+//	})
+type HedgePolicy struct {
+	// Delay is the duration to wait before sending the next attempt.
+	Delay time.Duration
+	// MaxAttempts is the maximum number of the attempts including the first one, default 2.
+	MaxAttempts int
+}
+
+// hedgeContextKey is the key of the context value for the number of the hedged attempt.
+type hedgeContextKey struct{}
+
+// defaultHedgeMaxAttempts is the default maximum number of the hedged attempts.
+const defaultHedgeMaxAttempts = 2
+
+// hedgeResult is the result of a hedged attempt.
+type hedgeResult struct {
+	attempt int
+	resp    *http.Response
+	err     error
+}
+
+// HedgeAttempt returns the number of the hedged attempt that the response is from, starting from
+// 1. It returns 0 if the request was not hedged.
+//
+//	resp, err := cli.GET("https://example.com")
+//	if err == nil && request.HedgeAttempt(resp) > 1 {
+//	  // the response is from a hedged attempt
+//	}
+func HedgeAttempt(resp *http.Response) int {
+	if resp == nil || resp.Request == nil {
+		return 0
+	}
+
+	attempt, _ := resp.Request.Context().Value(hedgeContextKey{}).(int)
+	return attempt
+}
+
+// getHedgePolicy returns the hedge policy from the request options or the client config.
+func (cli *Client) getHedgePolicy(opt RequestOptions) *HedgePolicy {
+	if opt.HedgePolicy != nil {
+		return opt.HedgePolicy
+	}
+
+	return cli.HedgePolicy
+}
+
+// sendRequestWithHedging sends the request with the hedged attempts by the hedge policy, and it
+// returns the first successful response. If all attempts fail, it returns the result of the last
+// completed attempt. No more attempt will be sent after all the sent attempts failed.
+func (cli *Client) sendRequestWithHedging(
+	req *http.Request,
+	opt RequestOptions,
+) (*http.Response, error) {
+	policy := cli.getHedgePolicy(opt)
+	if policy == nil || !isSafeMethod(req.Method) || !isRequestBodyReplayable(req) {
+		return cli.sendRequest(req, opt)
+	}
+
+	maxAttempts := policy.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultHedgeMaxAttempts
+	}
+	validateStatus := cli.getValidateStatus(opt)
+
+	results := make(chan hedgeResult, maxAttempts)
+	cancels := make([]context.CancelFunc, 0, maxAttempts)
+	launch := func() {
+		attempt := len(cancels) + 1
+		ctx, cancel := context.WithCancel(req.Context())
+		cancels = append(cancels, cancel)
+
+		r := req.Clone(context.WithValue(ctx, hedgeContextKey{}, attempt))
+		if attempt > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				results <- hedgeResult{attempt: attempt, err: err}
+				return
+			}
+			r.Body = body
+		}
+
+		go func() {
+			resp, err := cli.sendRequest(r, opt)
+			results <- hedgeResult{attempt: attempt, resp: resp, err: err}
+		}()
+	}
+
+	launch()
+	timer := time.NewTimer(policy.Delay)
+	defer timer.Stop()
+
+	var last hedgeResult
+	for pending := 1; pending > 0; {
+		timeout := timer.C
+		if len(cancels) >= maxAttempts {
+			timeout = nil
+		}
+
+		select {
+		case <-timeout:
+			launch()
+			pending++
+			timer.Reset(policy.Delay)
+		case result := <-results:
+			pending--
+			if result.err == nil && validateStatus(result.resp.StatusCode) {
+				finishHedging(results, cancels, result, pending)
+				return result.resp, nil
+			}
+
+			discardResponse(last.resp)
+			last = result
+		}
+	}
+
+	finishHedging(results, cancels, last, 0)
+
+	return last.resp, last.err
+}
+
+// finishHedging cancels the attempts except the chosen one, and discards the responses of the
+// pending attempts in the background. The context of the chosen attempt will be canceled when its
+// response body is closed.
+func finishHedging(
+	results <-chan hedgeResult,
+	cancels []context.CancelFunc,
+	chosen hedgeResult,
+	pending int,
+) {
+	for i, cancel := range cancels {
+		if i != chosen.attempt-1 {
+			cancel()
+		}
+	}
+	callOnBodyClose(chosen.resp, cancels[chosen.attempt-1])
+
+	if pending > 0 {
+		go func() {
+			for ; pending > 0; pending-- {
+				result := <-results
+				discardResponse(result.resp)
+			}
+		}()
+	}
+}
+
+// isSafeMethod checks whether the method is a safe method that doesn't change the state of the
+// server, and it's safe to send the request multiple times.
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	default:
+		return false
+	}
+}
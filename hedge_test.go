@@ -0,0 +1,90 @@
+package request
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ghosind/go-assert"
+)
+
+func TestHedgePolicy(t *testing.T) {
+	a := assert.New(t)
+
+	cli := New(Config{
+		HedgePolicy: &HedgePolicy{Delay: 20 * time.Millisecond},
+	})
+
+	start := time.Now()
+	resp, err := cli.GET("http://127.0.0.1:8080/hedge?key=hedge-slow&delays=500,0")
+	a.NilNow(err)
+	a.TrueNow(time.Since(start) < 300*time.Millisecond)
+	a.EqualNow(HedgeAttempt(resp), 2)
+	a.EqualNow(resp.Header.Get("X-Attempt"), "2")
+
+	data, _, err := ToObject[testResponse](resp, err)
+	a.NilNow(err)
+	a.EqualNow(*data.Path, "/hedge")
+
+	// no hedged attempt if the first attempt responds in time
+	resp, err = cli.GET("http://127.0.0.1:8080/hedge?key=hedge-fast")
+	a.NilNow(err)
+	resp.Body.Close()
+	a.EqualNow(HedgeAttempt(resp), 1)
+
+	time.Sleep(30 * time.Millisecond)
+	resp, err = cli.GET("http://127.0.0.1:8080/hedge?key=hedge-fast")
+	a.NilNow(err)
+	resp.Body.Close()
+	a.EqualNow(resp.Header.Get("X-Attempt"), "2")
+}
+
+func TestHedgePolicyMaxAttempts(t *testing.T) {
+	a := assert.New(t)
+
+	resp, err := Req("http://127.0.0.1:8080/hedge?key=hedge-max&delays=300,300,0,0").
+		SetHedgePolicy(HedgePolicy{Delay: 20 * time.Millisecond, MaxAttempts: 3}).
+		Do()
+	a.NilNow(err)
+	defer resp.Body.Close()
+	a.EqualNow(HedgeAttempt(resp), 3)
+	a.EqualNow(resp.Header.Get("X-Attempt"), "3")
+}
+
+func TestHedgePolicyWithFailures(t *testing.T) {
+	a := assert.New(t)
+
+	cli := New(Config{
+		HedgePolicy: &HedgePolicy{Delay: 20 * time.Millisecond, MaxAttempts: 3},
+	})
+
+	// it waits for the pending attempts if an attempt failed, and returns the last failure
+	start := time.Now()
+	resp, err := cli.GET("http://127.0.0.1:8080/hedge?key=hedge-fail&delays=80,0,0&status=500")
+	a.NotNilNow(err)
+	a.EqualNow(resp.StatusCode, http.StatusInternalServerError)
+	a.EqualNow(HedgeAttempt(resp), 1)
+	a.TrueNow(time.Since(start) >= 80*time.Millisecond)
+
+	// it returns immediately if all the sent attempts failed
+	resp, err = cli.GET("http://127.0.0.1:8080/status?status=503")
+	a.NotNilNow(err)
+	a.EqualNow(resp.StatusCode, http.StatusServiceUnavailable)
+	a.EqualNow(HedgeAttempt(resp), 1)
+}
+
+func TestHedgePolicyWithUnsafeMethod(t *testing.T) {
+	a := assert.New(t)
+
+	cli := New(Config{
+		HedgePolicy: &HedgePolicy{Delay: 10 * time.Millisecond},
+	})
+
+	resp, err := cli.POST("http://127.0.0.1:8080/hedge?key=hedge-post&delays=50,0")
+	a.NilNow(err)
+	defer resp.Body.Close()
+	a.EqualNow(HedgeAttempt(resp), 0)
+	a.EqualNow(resp.Header.Get("X-Attempt"), "1")
+
+	a.EqualNow(HedgeAttempt(nil), 0)
+}
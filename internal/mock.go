@@ -4,16 +4,34 @@ import (
 	"bytes"
 	"compress/flate"
 	"compress/gzip"
+	"compress/zlib"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"hash"
 	"io"
 	"net/http"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
 )
 
 type MockServer struct {
 	server *http.Server
+	// attempts are the number of requests for the retry handler, grouped by the key parameter.
+	attempts sync.Map
+	// tokens are the valid access tokens and refresh tokens that are issued by the OAuth 2.0 token
+	// handler.
+	tokens sync.Map
+	// tokenId is the counter for generating tokens.
+	tokenId atomic.Int64
 }
 
 func NewMockServer() *MockServer {
@@ -39,15 +57,369 @@ func (server *MockServer) Shutdown() {
 
 func (server *MockServer) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 	switch req.URL.Path {
+	case "/cache":
+		server.cacheHandler(rw, req)
+	case "/cookie":
+		server.cookieHandler(rw, req)
+	case "/delay":
+		server.delayHandler(rw, req)
+	case "/download":
+		server.downloadHandler(rw, req)
+	case "/digest":
+		server.digestHandler(rw, req)
+	case "/hedge":
+		server.hedgeHandler(rw, req)
+	case "/oauth2/token":
+		server.oauth2TokenHandler(rw, req)
+	case "/oauth2/resource":
+		server.oauth2ResourceHandler(rw, req)
+	case "/oauth2/revoke":
+		server.tokens.Delete("access:" + req.URL.Query().Get("token"))
 	case "/redirect":
 		server.redirectHandler(rw, req)
+	case "/retry":
+		server.retryHandler(rw, req)
+	case "/sse":
+		server.sseHandler(rw, req)
 	case "/status":
 		server.statusHandler(rw, req)
+	case "/stream":
+		server.streamHandler(rw, req)
 	default:
 		server.defaultHandler(rw, req)
 	}
 }
 
+// cacheHandler counts the requests by the `key` parameter and sets the number to the `X-Attempt`
+// field. It sets the `Cache-Control`, the `ETag`, and the `Vary` fields by the `cacheControl`,
+// `etag`, and `vary` parameters, and sets the `Last-Modified` field to an hour ago if the
+// `lastModified` parameter is set. It responds with the 304 status code if the validators of the
+// request match.
+func (server *MockServer) cacheHandler(rw http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+	etag := query.Get("etag")
+	lastModified := time.Now().Add(-time.Hour).UTC().Truncate(time.Hour).Format(http.TimeFormat)
+
+	counter, _ := server.attempts.LoadOrStore("cache:"+query.Get("key"), new(atomic.Int64))
+	attempt := counter.(*atomic.Int64).Add(1)
+	rw.Header().Set("X-Attempt", strconv.FormatInt(attempt, 10))
+
+	if cacheControl := query.Get("cacheControl"); cacheControl != "" {
+		rw.Header().Set("Cache-Control", cacheControl)
+	}
+	if vary := query.Get("vary"); vary != "" {
+		rw.Header().Set("Vary", vary)
+	}
+	if etag != "" {
+		rw.Header().Set("ETag", etag)
+		if req.Header.Get("If-None-Match") == etag {
+			rw.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+	if query.Has("lastModified") {
+		rw.Header().Set("Last-Modified", lastModified)
+		if req.Header.Get("If-Modified-Since") == lastModified {
+			rw.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+
+	server.defaultHandler(rw, req)
+}
+
+// cookieHandler sets the parameters as the cookies except the `maxAge` parameter, which is used as
+// the max age of the cookies, and then responds as the default handler.
+func (server *MockServer) cookieHandler(rw http.ResponseWriter, req *http.Request) {
+	maxAge := getIntParameter(req, "maxAge", 0)
+
+	for name, values := range req.URL.Query() {
+		if name == "maxAge" {
+			continue
+		}
+
+		http.SetCookie(rw, &http.Cookie{
+			Name:   name,
+			Value:  values[0],
+			Path:   "/",
+			MaxAge: int(maxAge),
+		})
+	}
+
+	server.defaultHandler(rw, req)
+}
+
+// delayHandler waits for the milliseconds of the `ms` parameter, and then responds as the status
+// handler if the `status` parameter is set, or as the default handler. It stops waiting if the
+// request is canceled.
+func (server *MockServer) delayHandler(rw http.ResponseWriter, req *http.Request) {
+	delay := time.Duration(getIntParameter(req, "ms", 0)) * time.Millisecond
+
+	select {
+	case <-time.After(delay):
+	case <-req.Context().Done():
+		return
+	}
+
+	if req.URL.Query().Has("status") {
+		server.statusHandler(rw, req)
+	} else {
+		server.defaultHandler(rw, req)
+	}
+}
+
+// downloadHandler serves the content of the `size` parameter (default 1024) bytes that the byte at
+// the offset i is i % 251, and supports the range requests with the `ETag` field "v1". It counts
+// the GET requests by the `key` parameter, and sets the number to the `X-Attempt` field. The ETag
+// changes for every request if the `changed` parameter is set, and the range requests are not
+// supported if the `noRange` parameter is set. The ETag is weak if the `weakETag` parameter is
+// set. The first GET request will be aborted after writing the bytes of the `failAt` parameter, or
+// stalled after writing the bytes of the `stallAt` parameter until the request is canceled.
+func (server *MockServer) downloadHandler(rw http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+	size := getIntParameter(req, "size", 1024)
+	failAt := getIntParameter(req, "failAt", -1)
+	stallAt := getIntParameter(req, "stallAt", -1)
+
+	content := make([]byte, size)
+	for i := range content {
+		content[i] = byte(i % 251)
+	}
+
+	attempt := int64(0)
+	if req.Method == http.MethodGet {
+		counter, _ := server.attempts.LoadOrStore("download:"+query.Get("key"), new(atomic.Int64))
+		attempt = counter.(*atomic.Int64).Add(1)
+		rw.Header().Set("X-Attempt", strconv.FormatInt(attempt, 10))
+	}
+
+	etag := `"v1"`
+	if query.Get("changed") != "" {
+		etag = fmt.Sprintf(`"v%d"`, attempt)
+	}
+	if query.Get("weakETag") != "" {
+		etag = "W/" + etag
+	}
+	rw.Header().Set("ETag", etag)
+
+	if attempt == 1 && failAt >= 0 {
+		rw = &abortWriter{ResponseWriter: rw, remaining: failAt}
+	} else if attempt == 1 && stallAt >= 0 {
+		rw = &abortWriter{ResponseWriter: rw, remaining: stallAt, stall: req.Context().Done()}
+	}
+
+	if query.Get("noRange") != "" {
+		rw.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+		rw.WriteHeader(http.StatusOK)
+		rw.Write(content)
+		return
+	}
+
+	http.ServeContent(rw, req, "", time.Time{}, bytes.NewReader(content))
+}
+
+// abortWriter is the response writer that aborts the response after writing the remaining bytes.
+// It waits for the stall channel before aborting if the channel is set.
+type abortWriter struct {
+	http.ResponseWriter
+	remaining int64
+	stall     <-chan struct{}
+}
+
+func (w *abortWriter) Write(p []byte) (int, error) {
+	if int64(len(p)) <= w.remaining {
+		w.remaining -= int64(len(p))
+		return w.ResponseWriter.Write(p)
+	}
+
+	w.ResponseWriter.Write(p[:w.remaining])
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	if w.stall != nil {
+		<-w.stall
+	}
+	panic(http.ErrAbortHandler)
+}
+
+// digestHandler checks the digest credentials of the user "user" with the password "pass", and
+// responds with the challenge if the credentials are missing or invalid. The algorithm and the qop
+// of the challenge are specified by the `algorithm` and `qop` parameters.
+func (server *MockServer) digestHandler(rw http.ResponseWriter, req *http.Request) {
+	const realm, nonce, opaque = "test@example.com", "dcd98b7102dd2f0e8b11d0f600bfb0c093",
+		"5ccc069c403ebaf9f0171e9517f40e41"
+
+	algorithm := req.URL.Query().Get("algorithm")
+	if algorithm == "" {
+		algorithm = "MD5"
+	}
+	qop := req.URL.Query().Get("qop")
+
+	payload, err := io.ReadAll(req.Body)
+	if err != nil {
+		panic(fmt.Sprintf("unexpected error: %v", err))
+	}
+	req.Body = io.NopCloser(bytes.NewReader(payload))
+
+	newHash := md5.New
+	if strings.HasPrefix(algorithm, "SHA-256") {
+		newHash = sha256.New
+	}
+	hashHex := func(data string) string {
+		h := newHash()
+		h.Write([]byte(data))
+		return hex.EncodeToString(h.Sum(nil))
+	}
+
+	params := parseDigestParams(req.Header.Get("Authorization"))
+	if params != nil && params["nonce"] == nonce && params["opaque"] == opaque {
+		ha1 := hashHex("user:" + realm + ":pass")
+		if strings.HasSuffix(algorithm, "-sess") {
+			ha1 = hashHex(ha1 + ":" + nonce + ":" + params["cnonce"])
+		}
+		a2 := req.Method + ":" + params["uri"]
+		if params["qop"] == "auth-int" {
+			a2 += ":" + hashBytes(newHash, payload)
+		}
+		ha2 := hashHex(a2)
+
+		expected := hashHex(ha1 + ":" + nonce + ":" + ha2)
+		if params["qop"] != "" {
+			expected = hashHex(strings.Join([]string{
+				ha1, nonce, params["nc"], params["cnonce"], params["qop"], ha2,
+			}, ":"))
+		}
+
+		if params["response"] == expected && params["uri"] == req.URL.RequestURI() {
+			server.defaultHandler(rw, req)
+			return
+		}
+	}
+
+	challenge := fmt.Sprintf(`Digest realm="%s", nonce="%s", opaque="%s", algorithm=%s`,
+		realm, nonce, opaque, algorithm)
+	if qop != "" {
+		challenge += fmt.Sprintf(`, qop="%s"`, qop)
+	}
+	rw.Header().Add("WWW-Authenticate", `Basic realm="`+realm+`"`)
+	rw.Header().Add("WWW-Authenticate", challenge)
+	rw.WriteHeader(http.StatusUnauthorized)
+}
+
+func parseDigestParams(authorization string) map[string]string {
+	if !strings.HasPrefix(authorization, "Digest ") {
+		return nil
+	}
+
+	params := make(map[string]string)
+	for _, param := range strings.Split(authorization[len("Digest "):], ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+		if ok {
+			params[key] = strings.Trim(value, `"`)
+		}
+	}
+
+	return params
+}
+
+func hashBytes(newHash func() hash.Hash, data []byte) string {
+	h := newHash()
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// hedgeHandler counts the requests by the `key` parameter, and waits for the milliseconds in the
+// comma-separated `delays` parameter by the number of the request before responding as the delay
+// handler. It sets the number of the request to the `X-Attempt` field.
+func (server *MockServer) hedgeHandler(rw http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+	delays := strings.Split(query.Get("delays"), ",")
+
+	counter, _ := server.attempts.LoadOrStore("hedge:"+query.Get("key"), new(atomic.Int64))
+	attempt := counter.(*atomic.Int64).Add(1)
+	rw.Header().Set("X-Attempt", strconv.FormatInt(attempt, 10))
+
+	delay := "0"
+	if int(attempt) <= len(delays) && delays[attempt-1] != "" {
+		delay = delays[attempt-1]
+	}
+	query.Set("ms", delay)
+	req.URL.RawQuery = query.Encode()
+
+	server.delayHandler(rw, req)
+}
+
+// oauth2TokenHandler issues the tokens for the client "client" with the secret "secret" by the
+// client credentials grant or the refresh token grant. The lifetime of the access tokens is
+// specified by the `expiresIn` parameter, default 3600 seconds. It waits for the milliseconds of
+// the `delay` parameter before responding.
+func (server *MockServer) oauth2TokenHandler(rw http.ResponseWriter, req *http.Request) {
+	time.Sleep(time.Duration(getIntParameter(req, "delay", 0)) * time.Millisecond)
+
+	if err := req.ParseForm(); err != nil {
+		writeOAuth2Error(rw, http.StatusBadRequest, "invalid_request")
+		return
+	}
+
+	clientId, clientSecret, ok := req.BasicAuth()
+	if !ok {
+		clientId, clientSecret = req.PostForm.Get("client_id"), req.PostForm.Get("client_secret")
+	}
+
+	switch req.PostForm.Get("grant_type") {
+	case "client_credentials":
+		if clientId != "client" || clientSecret != "secret" {
+			writeOAuth2Error(rw, http.StatusUnauthorized, "invalid_client")
+			return
+		}
+	case "refresh_token":
+		refreshToken := req.PostForm.Get("refresh_token")
+		if _, ok := server.tokens.LoadAndDelete("refresh:" + refreshToken); !ok {
+			writeOAuth2Error(rw, http.StatusBadRequest, "invalid_grant")
+			return
+		}
+	default:
+		writeOAuth2Error(rw, http.StatusBadRequest, "unsupported_grant_type")
+		return
+	}
+
+	id := server.tokenId.Add(1)
+	accessToken := fmt.Sprintf("access-token-%d", id)
+	refreshToken := fmt.Sprintf("refresh-token-%d", id)
+	server.tokens.Store("access:"+accessToken, true)
+	server.tokens.Store("refresh:"+refreshToken, true)
+
+	data, _ := json.Marshal(map[string]any{
+		"access_token":  accessToken,
+		"token_type":    "bearer",
+		"expires_in":    getIntParameter(req, "expiresIn", 3600),
+		"refresh_token": refreshToken,
+		"scope":         req.PostForm.Get("scope"),
+	})
+
+	rw.Header().Set("Content-Type", "application/json")
+	rw.Write(data)
+}
+
+// oauth2ResourceHandler responds as the default handler if the request is sent with a valid access
+// token, or responds with the 401 status code.
+func (server *MockServer) oauth2ResourceHandler(rw http.ResponseWriter, req *http.Request) {
+	token := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if _, ok := server.tokens.Load("access:" + token); !ok {
+		rw.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		rw.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	server.defaultHandler(rw, req)
+}
+
+func writeOAuth2Error(rw http.ResponseWriter, status int, code string) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(status)
+	rw.Write([]byte(fmt.Sprintf(`{"error":"%s"}`, code)))
+}
+
 func (server *MockServer) redirectHandler(rw http.ResponseWriter, req *http.Request) {
 	tried := getIntParameter(req, "tried", 0)
 
@@ -55,10 +427,138 @@ func (server *MockServer) redirectHandler(rw http.ResponseWriter, req *http.Requ
 	rw.WriteHeader(http.StatusFound)
 }
 
+// retryHandler responds with the status code in the `status` parameter (default 503) for the first
+// `failures` requests with the same `key` parameter, and then responds as the default handler.
+func (server *MockServer) retryHandler(rw http.ResponseWriter, req *http.Request) {
+	key := req.URL.Query().Get("key")
+	failures := getIntParameter(req, "failures", 1)
+	status := getIntParameter(req, "status", http.StatusServiceUnavailable)
+
+	counter, _ := server.attempts.LoadOrStore(key, new(atomic.Int64))
+	attempt := counter.(*atomic.Int64).Add(1)
+	rw.Header().Set("X-Attempt", strconv.FormatInt(attempt, 10))
+
+	if attempt <= failures {
+		if retryAfter := req.URL.Query().Get("retryAfter"); retryAfter != "" {
+			rw.Header().Set("Retry-After", retryAfter)
+		}
+		rw.WriteHeader(int(status))
+		return
+	}
+
+	server.defaultHandler(rw, req)
+}
+
+// sseHandler counts the connections by the `key` parameter, and sends the `events` parameter
+// (default 3) events with the IDs following the `Last-Event-ID` field as the Server-Sent Events
+// stream for the first `connections` parameter (default 1) connections. It responds with the 204
+// status code for the later connections. It sets the reconnection time by the `retry` parameter,
+// ends the lines with CRLF if the `crlf` parameter is set, and keeps the connection open until the
+// client disconnects if the `hold` parameter is set.
+func (server *MockServer) sseHandler(rw http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+	events := getIntParameter(req, "events", 3)
+	connections := getIntParameter(req, "connections", 1)
+
+	counter, _ := server.attempts.LoadOrStore("sse:"+query.Get("key"), new(atomic.Int64))
+	attempt := counter.(*atomic.Int64).Add(1)
+	if attempt > connections {
+		rw.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	id := int64(0)
+	if lastEventID := req.Header.Get("Last-Event-ID"); lastEventID != "" {
+		id, _ = strconv.ParseInt(lastEventID, 10, 64)
+		id++
+	}
+
+	newline := "\n"
+	if query.Get("crlf") != "" {
+		newline = "\r\n"
+	}
+
+	rw.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
+	rw.WriteHeader(http.StatusOK)
+
+	flusher, _ := rw.(http.Flusher)
+	rw.Write([]byte(": connected" + newline))
+	if retry := query.Get("retry"); retry != "" {
+		rw.Write([]byte("retry: " + retry + newline + newline))
+	}
+	for i := int64(0); i < events; i++ {
+		fmt.Fprintf(rw, "id: %d%sevent: tick%sdata: event %d%sdata: attempt %d%s%s",
+			id+i, newline, newline, id+i, newline, attempt, newline, newline)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	if query.Get("hold") != "" {
+		<-req.Context().Done()
+	}
+}
+
 func (server *MockServer) statusHandler(rw http.ResponseWriter, req *http.Request) {
 	status := getIntParameter(req, "status", 200)
 
 	rw.WriteHeader(int(status))
+	if message := req.URL.Query().Get("message"); message != "" {
+		rw.Write([]byte(message))
+	}
+}
+
+// streamHandler responds with a JSON array of the objects with the `id` field from 0 to the
+// `count` parameter, and flushes after writing every object. It responds with the objects as the
+// newline-delimited JSON stream if the `ndjson` parameter is set, and writes an invalid line
+// before the object with the ID in the `invalid` parameter. It waits for the milliseconds of the
+// `interval` parameter before writing every object.
+func (server *MockServer) streamHandler(rw http.ResponseWriter, req *http.Request) {
+	count := getIntParameter(req, "count", 0)
+	interval := time.Duration(getIntParameter(req, "interval", 0)) * time.Millisecond
+	ndjson := req.URL.Query().Get("ndjson") != ""
+	invalid := getIntParameter(req, "invalid", -1)
+
+	if ndjson {
+		rw.Header().Set("Content-Type", "application/x-ndjson")
+	} else {
+		rw.Header().Set("Content-Type", "application/json")
+	}
+	rw.WriteHeader(http.StatusOK)
+
+	flusher, _ := rw.(http.Flusher)
+	if !ndjson {
+		rw.Write([]byte("["))
+	}
+	if flusher != nil {
+		flusher.Flush()
+	}
+	for i := int64(0); i < count; i++ {
+		if interval > 0 {
+			select {
+			case <-time.After(interval):
+			case <-req.Context().Done():
+				return
+			}
+		}
+		if ndjson {
+			if i == invalid {
+				rw.Write([]byte("invalid\n"))
+			}
+		} else if i > 0 {
+			rw.Write([]byte(","))
+		}
+		fmt.Fprintf(rw, `{"id":%d}`, i)
+		if ndjson {
+			rw.Write([]byte("\n"))
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	if !ndjson {
+		rw.Write([]byte("]"))
+	}
 }
 
 func (server *MockServer) defaultHandler(rw http.ResponseWriter, req *http.Request) {
@@ -113,43 +613,66 @@ func getIntParameter(req *http.Request, key string, defaultValue int64) int64 {
 	return intValue
 }
 
+// encodingResponse encodes the data by the first content encoding in the `Accept-Encoding` field,
+// or by all the comma-separated content encodings in the `encoding` parameter in order.
 func encodingResponse(rw http.ResponseWriter, req *http.Request, data []byte) ([]byte, error) {
-	encodings := strings.Split(req.Header.Get("Accept-Encoding"), ",")
-	encoding := encodings[0]
+	encodings := strings.Split(req.Header.Get("Accept-Encoding"), ",")[:1]
+	if encoding := req.URL.Query().Get("encoding"); encoding != "" {
+		encodings = strings.Split(encoding, ",")
+	}
 
 	contentEncoding := req.URL.Query().Get("contentEncoding")
 
-	if encoding != "" {
+	if encodings[0] != "" {
 		if contentEncoding != "" {
 			rw.Header().Set("Content-Encoding", contentEncoding) // for test error case
 		} else {
-			rw.Header().Set("Content-Encoding", encoding)
+			rw.Header().Set("Content-Encoding", strings.Join(encodings, ", "))
 		}
 	}
 
-	switch encoding {
-	case "deflate":
-		buf := bytes.NewBuffer([]byte{})
-		writer, err := flate.NewWriter(buf, -1)
+	for _, encoding := range encodings {
+		var err error
+		data, err = encodeData(strings.TrimSpace(encoding), data)
 		if err != nil {
 			return nil, err
 		}
-		if _, err := writer.Write(data); err != nil {
+	}
+
+	return data, nil
+}
+
+func encodeData(encoding string, data []byte) ([]byte, error) {
+	buf := bytes.NewBuffer([]byte{})
+	var writer io.WriteCloser
+
+	switch encoding {
+	case "br":
+		writer = brotli.NewWriter(buf)
+	case "deflate":
+		flateWriter, err := flate.NewWriter(buf, -1)
+		if err != nil {
 			return nil, err
 		}
-		writer.Close()
-
-		return buf.Bytes(), nil
+		writer = flateWriter
 	case "gzip", "x-gzip":
-		buf := bytes.NewBuffer([]byte{})
-		writer := gzip.NewWriter(buf)
-		if _, err := writer.Write(data); err != nil {
+		writer = gzip.NewWriter(buf)
+	case "zlib":
+		writer = zlib.NewWriter(buf)
+	case "zstd":
+		zstdWriter, err := zstd.NewWriter(buf)
+		if err != nil {
 			return nil, err
 		}
-		writer.Close()
-
-		return buf.Bytes(), nil
+		writer = zstdWriter
 	default:
 		return data, nil
 	}
+
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	writer.Close()
+
+	return buf.Bytes(), nil
 }
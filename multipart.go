@@ -0,0 +1,187 @@
+package request
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// MultipartForm is the builder of a `multipart/form-data` request body. The parts will be written
+// into the request body in the order they were added, and the content of the parts will be
+// streamed to the server without buffering.
+//
+// The readers of the parts will be consumed after the request is sent, so a MultipartForm can
+// only be used for one request. The readers will not be closed by this package, and you need to
+// close them (for example, the opened files) by yourself.
+//
+//	form := request.NewMultipartForm()
+//	form.AddField("name", "avatar")
+//	form.AddFile("file", "avatar.png", file)
+//
+//	resp, err := request.POST("http://example.com/upload", request.RequestOptions{
+//	  ContentType: request.RequestContentTypeMultipart,
+//	  Multipart:   form,
+//	})
+type MultipartForm struct {
+	// boundary is the boundary string that is used to separate the parts.
+	boundary string
+	// parts are the parts of the form.
+	parts []*multipartPart
+}
+
+// multipartPart is a part of the multipart form.
+type multipartPart struct {
+	// header is the MIME header of the part.
+	header textproto.MIMEHeader
+	// content is the reader of the part's content.
+	content io.Reader
+}
+
+// quoteEscaper escapes the quotes and the backslashes in the field name and the file name.
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// NewMultipartForm creates a new empty multipart form with a random boundary.
+func NewMultipartForm() *MultipartForm {
+	form := new(MultipartForm)
+
+	form.Boundary()
+	form.parts = make([]*multipartPart, 0)
+
+	return form
+}
+
+// Boundary returns the boundary string of the multipart form.
+func (form *MultipartForm) Boundary() string {
+	if form.boundary == "" {
+		form.boundary = multipart.NewWriter(io.Discard).Boundary()
+	}
+
+	return form.boundary
+}
+
+// FormDataContentType returns the value of the `Content-Type` field with the boundary of the
+// multipart form.
+func (form *MultipartForm) FormDataContentType() string {
+	return "multipart/form-data; boundary=" + form.Boundary()
+}
+
+// AddField adds a text field with the name and the value to the form.
+func (form *MultipartForm) AddField(name, value string) *MultipartForm {
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition",
+		fmt.Sprintf(`form-data; name="%s"`, quoteEscaper.Replace(name)))
+
+	return form.AddPart(header, strings.NewReader(value))
+}
+
+// AddFile adds a file field with the field name, the file name, and the content to the form. The
+// `Content-Type` of the part will be detected by the extension of the file name, and it will be
+// `application/octet-stream` if the type is unknown.
+func (form *MultipartForm) AddFile(field, filename string, content io.Reader) *MultipartForm {
+	contentType := mime.TypeByExtension(filepath.Ext(filename))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+		quoteEscaper.Replace(field), quoteEscaper.Replace(filename)))
+	header.Set("Content-Type", contentType)
+
+	return form.AddPart(header, content)
+}
+
+// AddPart adds a part with the custom MIME header and the content to the form.
+//
+//	header := make(textproto.MIMEHeader)
+//	header.Set("Content-Disposition", `form-data; name="metadata"`)
+//	header.Set("Content-Type", "application/json")
+//	form.AddPart(header, strings.NewReader(`{"name":"avatar"}`))
+func (form *MultipartForm) AddPart(
+	header textproto.MIMEHeader,
+	content io.Reader,
+) *MultipartForm {
+	form.parts = append(form.parts, &multipartPart{
+		header:  header,
+		content: content,
+	})
+
+	return form
+}
+
+// reader returns a reader of the form's content. It writes the fields and the parts into a pipe
+// in a new goroutine, and the goroutine will exit after all the parts are written, or the reader
+// is closed.
+func (form *MultipartForm) reader(fields url.Values) io.ReadCloser {
+	pr, pw := io.Pipe()
+
+	go func() {
+		pw.CloseWithError(form.writeTo(pw, fields))
+	}()
+
+	return pr
+}
+
+// writeTo writes the fields and the parts of the form into the writer.
+func (form *MultipartForm) writeTo(w io.Writer, fields url.Values) error {
+	writer := multipart.NewWriter(w)
+	if err := writer.SetBoundary(form.Boundary()); err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		for _, v := range fields[k] {
+			if err := writer.WriteField(k, v); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, part := range form.parts {
+		pw, err := writer.CreatePart(part.header)
+		if err != nil {
+			return err
+		}
+
+		if part.content != nil {
+			if _, err := io.Copy(pw, part.content); err != nil {
+				return err
+			}
+		}
+	}
+
+	return writer.Close()
+}
+
+// getMultipartBody returns the reader of the multipart form in the request options. The value of
+// the `Body` field will be encoded as the text fields of the form, and it can be an `url.Values`,
+// a map, or a struct with the `form` tags.
+func (cli *Client) getMultipartBody(opt RequestOptions) (io.Reader, error) {
+	var fields url.Values
+	if opt.Body != nil {
+		values, err := toFormValues(opt.Body)
+		if err != nil {
+			return nil, err
+		}
+		fields = values
+	}
+
+	form := opt.Multipart
+	if form == nil {
+		form = NewMultipartForm()
+	}
+
+	return form.reader(fields), nil
+}
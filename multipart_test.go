@@ -0,0 +1,114 @@
+package request
+
+import (
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"github.com/ghosind/go-assert"
+)
+
+func TestMultipartForm(t *testing.T) {
+	a := assert.New(t)
+
+	form := NewMultipartForm()
+	a.NotEqualNow(form.Boundary(), "")
+	a.EqualNow(form.FormDataContentType(), "multipart/form-data; boundary="+form.Boundary())
+
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", `form-data; name="metadata"`)
+	header.Set("Content-Type", "application/json")
+
+	form.AddField("name", "test").
+		AddFile("file", "test.txt", strings.NewReader("Hello world!")).
+		AddPart(header, strings.NewReader(`{"size":12}`))
+
+	data, err := io.ReadAll(form.reader(map[string][]string{"id": {"1"}}))
+	a.NilNow(err)
+
+	parts := readMultipartParts(a, string(data), form.Boundary())
+	a.EqualNow(len(parts), 4)
+	a.EqualNow(parts[0], [3]string{"id", "", "1"})
+	a.EqualNow(parts[1], [3]string{"name", "", "test"})
+	a.EqualNow(parts[2], [3]string{"file", "test.txt", "Hello world!"})
+	a.EqualNow(parts[3], [3]string{"metadata", "", `{"size":12}`})
+}
+
+func TestMultipartFormWithZeroValue(t *testing.T) {
+	a := assert.New(t)
+
+	form := new(MultipartForm)
+	form.AddField("name", "test")
+
+	data, err := io.ReadAll(form.reader(nil))
+	a.NilNow(err)
+
+	parts := readMultipartParts(a, string(data), form.Boundary())
+	a.EqualNow(len(parts), 1)
+	a.EqualNow(parts[0], [3]string{"name", "", "test"})
+}
+
+func TestMultipartRequest(t *testing.T) {
+	a := assert.New(t)
+
+	data, _, err := ToObject[testResponse](Req("http://localhost:8080").
+		POST().
+		SetBody(map[string]string{"id": "1"}).
+		AddFormField("name", "test").
+		AddFile("file", "test.txt", strings.NewReader("Hello world!")).
+		Do())
+	a.NilNow(err)
+	a.NotNilNow(data.ContentType)
+	a.NotNilNow(data.Body)
+
+	mediaType, params, err := mime.ParseMediaType(*data.ContentType)
+	a.NilNow(err)
+	a.EqualNow(mediaType, "multipart/form-data")
+
+	parts := readMultipartParts(a, *data.Body, params["boundary"])
+	a.EqualNow(len(parts), 3)
+	a.EqualNow(parts[0], [3]string{"id", "", "1"})
+	a.EqualNow(parts[1], [3]string{"name", "", "test"})
+	a.EqualNow(parts[2], [3]string{"file", "test.txt", "Hello world!"})
+
+	data, _, err = ToObject[testResponse](POST("http://localhost:8080", RequestOptions{
+		ContentType: RequestContentTypeMultipart,
+		Body:        map[string]string{"id": "1"},
+	}))
+	a.NilNow(err)
+
+	_, params, err = mime.ParseMediaType(*data.ContentType)
+	a.NilNow(err)
+	parts = readMultipartParts(a, *data.Body, params["boundary"])
+	a.EqualNow(len(parts), 1)
+	a.EqualNow(parts[0], [3]string{"id", "", "1"})
+
+	_, err = POST("http://localhost:8080", RequestOptions{
+		ContentType: RequestContentTypeMultipart,
+		Body:        1,
+	})
+	a.NotNilNow(err)
+}
+
+func readMultipartParts(a *assert.Assertion, data, boundary string) [][3]string {
+	reader := multipart.NewReader(strings.NewReader(data), boundary)
+	parts := make([][3]string, 0)
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		a.NilNow(err)
+
+		content, err := io.ReadAll(part)
+		a.NilNow(err)
+
+		parts = append(parts, [3]string{part.FormName(), part.FileName(), string(content)})
+	}
+
+	return parts
+}
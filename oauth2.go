@@ -0,0 +1,310 @@
+package request
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OAuth2Auth is the authenticator that gets the access tokens from the token endpoint of an OAuth
+// 2.0 authorization server, by the client credentials grant or the refresh token grant. The token
+// is cached until it's about to expire, and it'll be refreshed by the refresh token if the server
+// issued one. If the server responds to a request with the 401 (Unauthorized) status code, the
+// token will be dropped, and the request will be re-sent once with a new token.
+//
+// It's safe for concurrent use, and the requests will share the same token. The same
+// `OAuth2Auth` should be used by pointer.
+//
+//	cli := request.New(request.Config{
+//	  Authenticator: &request.OAuth2Auth{
+//	    TokenURL:     "https://auth.example.com/oauth2/token",
+//	    ClientID:     "client-id",
+//	    ClientSecret: "client-secret",
+//	    Scopes:       []string{"read", "write"},
+//	  },
+//	})
+type OAuth2Auth struct {
+	// TokenURL is the URL of the token endpoint of the authorization server.
+	TokenURL string
+	// ClientID is the client identifier.
+	ClientID string
+	// ClientSecret is the client secret.
+	ClientSecret string
+	// Scopes are the scopes of the access request.
+	Scopes []string
+	// RefreshToken is the refresh token to get the access tokens by the refresh token grant, and it
+	// uses the client credentials grant if it's empty.
+	RefreshToken string
+	// EndpointParams are the additional parameters to be sent to the token endpoint, like
+	// "audience".
+	EndpointParams map[string][]string
+	// UseBasicAuth indicates whether to send the client credentials by HTTP Basic Auth instead of
+	// the request body.
+	UseBasicAuth bool
+	// ExpiryDelta is the duration before the expiration time of the token to refresh it, default
+	// 10 seconds.
+	ExpiryDelta time.Duration
+	// Client is the client to send the token requests, default is the package's default client.
+	// It should not use this authenticator, or the token requests will never be sent. The token
+	// requests are sent with the timeout of the client, and they're not canceled by the requests
+	// that are waiting for the token.
+	Client *Client
+
+	// token is the cached token.
+	token *OAuth2Token
+	// refreshToken is the last refresh token that the server issued.
+	refreshToken string
+	// fetching is the in-flight token request, and it's shared by the concurrent callers.
+	fetching *oauth2Fetch
+	// mutex is the locker for the token.
+	mutex sync.Mutex
+}
+
+// oauth2Fetch is an in-flight token request, and the done channel will be closed when the request
+// is completed.
+type oauth2Fetch struct {
+	done  chan struct{}
+	token *OAuth2Token
+	err   error
+}
+
+// OAuth2Token is the access token that is issued by the authorization server.
+type OAuth2Token struct {
+	// AccessToken is the access token.
+	AccessToken string `json:"access_token"`
+	// TokenType is the type of the token, default "Bearer".
+	TokenType string `json:"token_type,omitempty"`
+	// RefreshToken is the token to get a new access token when the access token expires.
+	RefreshToken string `json:"refresh_token,omitempty"`
+	// ExpiresIn is the lifetime in seconds of the access token.
+	ExpiresIn int64 `json:"expires_in,omitempty"`
+	// Scope is the scope of the access token.
+	Scope string `json:"scope,omitempty"`
+	// Expiry is the expiration time of the access token, and the token never expires if it's zero.
+	Expiry time.Time `json:"-"`
+}
+
+// defaultOAuth2ExpiryDelta is the default duration before the expiration time to refresh tokens.
+const defaultOAuth2ExpiryDelta = 10 * time.Second
+
+// Authenticate sets the access token to the request, and it'll get a new token if there is no
+// valid token.
+func (auth *OAuth2Auth) Authenticate(req *http.Request) error {
+	token, err := auth.Token(req.Context())
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", token.authorization())
+
+	return nil
+}
+
+// Challenge drops the cached token and returns true to re-send the request with a new token if
+// the request was sent with the cached token. It'll not re-send the request if the request was
+// sent with another token, or the server indicates that the token has insufficient scope.
+func (auth *OAuth2Auth) Challenge(req *http.Request, resp *http.Response) (bool, error) {
+	if getBearerChallengeError(resp) == "insufficient_scope" {
+		return false, nil
+	}
+
+	auth.mutex.Lock()
+	defer auth.mutex.Unlock()
+
+	if auth.token == nil || req.Header.Get("Authorization") != auth.token.authorization() {
+		return false, nil
+	}
+	auth.token = nil
+
+	return true, nil
+}
+
+// Token returns the cached token if it's valid, or gets a new token from the token endpoint. The
+// concurrent callers share the same token request, and the context only stops waiting for the
+// token.
+func (auth *OAuth2Auth) Token(ctx context.Context) (*OAuth2Token, error) {
+	auth.mutex.Lock()
+	if auth.isTokenValid() {
+		token := *auth.token
+		auth.mutex.Unlock()
+		return &token, nil
+	}
+
+	fetch := auth.fetching
+	if fetch == nil {
+		fetch = &oauth2Fetch{done: make(chan struct{})}
+		auth.fetching = fetch
+		go auth.fetch(fetch, auth.getRefreshToken())
+	}
+	auth.mutex.Unlock()
+
+	select {
+	case <-fetch.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	if fetch.err != nil {
+		return nil, fetch.err
+	}
+	token := *fetch.token
+	return &token, nil
+}
+
+// fetch gets a new token and caches it, and then notifies the callers that are waiting for the
+// token.
+func (auth *OAuth2Auth) fetch(fetch *oauth2Fetch, refreshToken string) {
+	token, fallback, err := auth.fetchToken(refreshToken)
+
+	auth.mutex.Lock()
+	defer auth.mutex.Unlock()
+
+	if fallback {
+		auth.refreshToken = ""
+	}
+	if err == nil {
+		auth.token = token
+		if token.RefreshToken != "" {
+			auth.refreshToken = token.RefreshToken
+		}
+	}
+	auth.fetching = nil
+
+	fetch.token, fetch.err = token, err
+	close(fetch.done)
+}
+
+// getRefreshToken returns the last refresh token that the server issued or the refresh token of
+// the settings, and the caller must hold the lock.
+func (auth *OAuth2Auth) getRefreshToken() string {
+	if auth.refreshToken != "" {
+		return auth.refreshToken
+	}
+
+	return auth.RefreshToken
+}
+
+// isTokenValid checks whether the cached token exists and does not expire in the expiry delta.
+func (auth *OAuth2Auth) isTokenValid() bool {
+	if auth.token == nil {
+		return false
+	} else if auth.token.Expiry.IsZero() {
+		return true
+	}
+
+	delta := auth.ExpiryDelta
+	if delta <= 0 {
+		delta = defaultOAuth2ExpiryDelta
+	}
+
+	return time.Now().Add(delta).Before(auth.token.Expiry)
+}
+
+// fetchToken gets a new token by the refresh token grant if there is a refresh token, or by the
+// client credentials grant. It'll fall back to the client credentials grant if it fails to
+// refresh the token that is issued by the client credentials grant, and it returns true for the
+// fallback to drop the refresh token.
+func (auth *OAuth2Auth) fetchToken(refreshToken string) (*OAuth2Token, bool, error) {
+	fallback := false
+	if refreshToken != "" {
+		token, err := auth.requestToken(url.Values{
+			"grant_type":    {"refresh_token"},
+			"refresh_token": {refreshToken},
+		})
+		if err == nil || auth.RefreshToken != "" {
+			return token, false, err
+		}
+		fallback = true
+	}
+
+	params := url.Values{"grant_type": {"client_credentials"}}
+	if len(auth.Scopes) > 0 {
+		params.Set("scope", strings.Join(auth.Scopes, " "))
+	}
+
+	token, err := auth.requestToken(params)
+	return token, fallback, err
+}
+
+// requestToken sends the token request with the parameters to the token endpoint.
+func (auth *OAuth2Auth) requestToken(params url.Values) (*OAuth2Token, error) {
+	for k, v := range auth.EndpointParams {
+		params[k] = v
+	}
+
+	opt := RequestOptions{
+		Body:        params,
+		ContentType: RequestContentTypeForm,
+		Headers:     map[string][]string{"Accept": {"application/json"}},
+	}
+	if auth.UseBasicAuth {
+		opt.Auth = &BasicAuthConfig{
+			Username: url.QueryEscape(auth.ClientID),
+			Password: url.QueryEscape(auth.ClientSecret),
+		}
+	} else {
+		params.Set("client_id", auth.ClientID)
+		if auth.ClientSecret != "" {
+			params.Set("client_secret", auth.ClientSecret)
+		}
+	}
+
+	cli := auth.Client
+	if cli == nil {
+		cli = defaultClient
+	}
+
+	resp, err := cli.POST(auth.TokenURL, opt)
+	if err != nil {
+		if resp != nil {
+			resp.Body.Close()
+		}
+		return nil, fmt.Errorf("failed to get oauth2 token: %w", err)
+	}
+
+	token, _, err := ToObject[OAuth2Token](resp, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get oauth2 token: %w", err)
+	} else if token == nil || token.AccessToken == "" {
+		return nil, fmt.Errorf("%w: no access token in the response", ErrInvalidResp)
+	}
+
+	if token.ExpiresIn > 0 {
+		token.Expiry = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	}
+
+	return token, nil
+}
+
+// authorization returns the value of the `Authorization` field for the token.
+func (token *OAuth2Token) authorization() string {
+	tokenType := token.TokenType
+	if tokenType == "" || strings.EqualFold(tokenType, "bearer") {
+		tokenType = "Bearer"
+	}
+
+	return tokenType + " " + token.AccessToken
+}
+
+// getBearerChallengeError returns the error code of the Bearer challenge in the `WWW-Authenticate`
+// fields of the response headers (RFC 6750).
+func getBearerChallengeError(resp *http.Response) string {
+	if resp == nil {
+		return ""
+	}
+
+	for _, value := range resp.Header.Values("WWW-Authenticate") {
+		i := strings.Index(strings.ToLower(value), "bearer ")
+		if i < 0 || (i > 0 && value[i-1] != ' ' && value[i-1] != ',') {
+			continue
+		}
+
+		return parseAuthParams(value[i+len("bearer "):])["error"]
+	}
+
+	return ""
+}
@@ -0,0 +1,247 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ghosind/go-assert"
+)
+
+const (
+	oauth2TokenURL    = "http://127.0.0.1:8080/oauth2/token"
+	oauth2ResourceURL = "http://127.0.0.1:8080/oauth2/resource"
+)
+
+func TestOAuth2ClientCredentials(t *testing.T) {
+	a := assert.New(t)
+
+	auth := &OAuth2Auth{
+		TokenURL:     oauth2TokenURL,
+		ClientID:     "client",
+		ClientSecret: "secret",
+		Scopes:       []string{"read", "write"},
+	}
+	cli := New(Config{Authenticator: auth})
+
+	data, _, err := ToObject[testResponse](cli.GET(oauth2ResourceURL))
+	a.NilNow(err)
+
+	token, err := auth.Token(context.Background())
+	a.NilNow(err)
+	a.EqualNow(*data.Token, "Bearer "+token.AccessToken)
+	a.EqualNow(token.Scope, "read write")
+	a.TrueNow(token.Expiry.After(time.Now()))
+
+	// reuse the cached token
+	data, _, err = ToObject[testResponse](cli.GET(oauth2ResourceURL))
+	a.NilNow(err)
+	a.EqualNow(*data.Token, "Bearer "+token.AccessToken)
+}
+
+func TestOAuth2WithBasicAuth(t *testing.T) {
+	a := assert.New(t)
+
+	_, err := GET(oauth2ResourceURL, RequestOptions{
+		Authenticator: &OAuth2Auth{
+			TokenURL:     oauth2TokenURL,
+			ClientID:     "client",
+			ClientSecret: "secret",
+			UseBasicAuth: true,
+			Client:       New(),
+		},
+	})
+	a.NilNow(err)
+}
+
+func TestOAuth2RefreshToken(t *testing.T) {
+	a := assert.New(t)
+
+	// the token expires in the expiry delta, so it'll be refreshed for every request
+	auth := &OAuth2Auth{
+		TokenURL:     oauth2TokenURL + "?expiresIn=5",
+		ClientID:     "client",
+		ClientSecret: "secret",
+	}
+
+	first, err := auth.Token(context.Background())
+	a.NilNow(err)
+	second, err := auth.Token(context.Background())
+	a.NilNow(err)
+	a.NotEqualNow(second.AccessToken, first.AccessToken)
+
+	// the refresh token has been used, and it'll fall back to the client credentials grant
+	auth.refreshToken = first.RefreshToken
+	auth.token = nil
+	third, err := auth.Token(context.Background())
+	a.NilNow(err)
+	a.NotEqualNow(third.AccessToken, second.AccessToken)
+
+	// with the refresh token only
+	auth = &OAuth2Auth{
+		TokenURL:     oauth2TokenURL,
+		RefreshToken: third.RefreshToken,
+	}
+	_, err = GET(oauth2ResourceURL, RequestOptions{Authenticator: auth})
+	a.NilNow(err)
+
+	auth = &OAuth2Auth{
+		TokenURL:     oauth2TokenURL,
+		RefreshToken: third.RefreshToken,
+	}
+	_, err = GET(oauth2ResourceURL, RequestOptions{Authenticator: auth})
+	a.NotNilNow(err)
+}
+
+func TestOAuth2RetryOnUnauthorized(t *testing.T) {
+	a := assert.New(t)
+
+	auth := &OAuth2Auth{
+		TokenURL:     oauth2TokenURL,
+		ClientID:     "client",
+		ClientSecret: "secret",
+	}
+	cli := New(Config{Authenticator: auth})
+
+	token, err := auth.Token(context.Background())
+	a.NilNow(err)
+
+	_, err = GET("http://127.0.0.1:8080/oauth2/revoke?token=" + token.AccessToken)
+	a.NilNow(err)
+
+	data, resp, err := ToObject[testResponse](cli.GET(oauth2ResourceURL))
+	a.NilNow(err)
+	a.EqualNow(resp.StatusCode, http.StatusOK)
+	a.NotEqualNow(*data.Token, "Bearer "+token.AccessToken)
+}
+
+func TestOAuth2ConcurrentRequests(t *testing.T) {
+	a := assert.New(t)
+
+	auth := &OAuth2Auth{
+		TokenURL:     oauth2TokenURL,
+		ClientID:     "client",
+		ClientSecret: "secret",
+	}
+	cli := New(Config{Authenticator: auth})
+
+	tokens := make([]string, 10)
+	wg := sync.WaitGroup{}
+	for i := range tokens {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			data, _, err := ToObject[testResponse](cli.GET(oauth2ResourceURL))
+			if err == nil {
+				tokens[i] = *data.Token
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for _, token := range tokens {
+		a.EqualNow(token, tokens[0])
+	}
+	a.NotEqualNow(tokens[0], "")
+}
+
+func TestOAuth2SharedTokenRequest(t *testing.T) {
+	a := assert.New(t)
+
+	tokenCli := New()
+	requests := int32(0)
+	tokenCli.UseResponseInterceptor(func(resp *http.Response) error {
+		atomic.AddInt32(&requests, 1)
+		return nil
+	})
+	auth := &OAuth2Auth{
+		TokenURL:     oauth2TokenURL + "?delay=200",
+		ClientID:     "client",
+		ClientSecret: "secret",
+		Client:       tokenCli,
+	}
+
+	// the token request is not canceled by the caller
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := auth.Token(ctx)
+	a.TrueNow(errors.Is(err, context.DeadlineExceeded))
+
+	token, err := auth.Token(context.Background())
+	a.NilNow(err)
+	a.NotEqualNow(token.AccessToken, "")
+	a.EqualNow(atomic.LoadInt32(&requests), int32(1))
+}
+
+func TestOAuth2Challenge(t *testing.T) {
+	a := assert.New(t)
+
+	auth := &OAuth2Auth{
+		TokenURL:     oauth2TokenURL,
+		ClientID:     "client",
+		ClientSecret: "secret",
+	}
+	token, err := auth.Token(context.Background())
+	a.NilNow(err)
+
+	req, _ := http.NewRequest(http.MethodGet, oauth2ResourceURL, nil)
+	resp := &http.Response{Header: http.Header{}}
+
+	// the request was sent with another token
+	req.Header.Set("Authorization", "Bearer other")
+	retry, err := auth.Challenge(req, resp)
+	a.NilNow(err)
+	a.NotTrueNow(retry)
+
+	// the token has insufficient scope
+	req.Header.Set("Authorization", token.authorization())
+	resp.Header.Set("WWW-Authenticate", `Bearer error="insufficient_scope", scope="admin"`)
+	retry, err = auth.Challenge(req, resp)
+	a.NilNow(err)
+	a.NotTrueNow(retry)
+
+	resp.Header.Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+	retry, err = auth.Challenge(req, resp)
+	a.NilNow(err)
+	a.TrueNow(retry)
+
+	newToken, err := auth.Token(context.Background())
+	a.NilNow(err)
+	a.NotEqualNow(newToken.AccessToken, token.AccessToken)
+}
+
+func TestOAuth2InvalidClient(t *testing.T) {
+	a := assert.New(t)
+
+	_, err := GET(oauth2ResourceURL, RequestOptions{
+		Authenticator: &OAuth2Auth{
+			TokenURL:     oauth2TokenURL,
+			ClientID:     "client",
+			ClientSecret: "wrong",
+		},
+	})
+	a.NotNilNow(err)
+
+	var respErr *ResponseError
+	a.TrueNow(errors.As(err, &respErr))
+	a.EqualNow(respErr.StatusCode, http.StatusUnauthorized)
+	a.EqualNow(string(respErr.Body), `{"error":"invalid_client"}`)
+
+	_, err = GET(oauth2ResourceURL, RequestOptions{
+		Authenticator: &OAuth2Auth{TokenURL: "http://127.0.0.1:8080/status"},
+	})
+	a.TrueNow(errors.Is(err, ErrInvalidResp))
+}
+
+func TestOAuth2TokenAuthorization(t *testing.T) {
+	a := assert.New(t)
+
+	a.EqualNow((&OAuth2Token{AccessToken: "t"}).authorization(), "Bearer t")
+	a.EqualNow((&OAuth2Token{AccessToken: "t", TokenType: "bearer"}).authorization(), "Bearer t")
+	a.EqualNow((&OAuth2Token{AccessToken: "t", TokenType: "MAC"}).authorization(), "MAC t")
+}
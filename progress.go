@@ -0,0 +1,167 @@
+package request
+
+import (
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Progress is the progress of transferring a body.
+type Progress struct {
+	// Transferred is the number of bytes that have been transferred.
+	Transferred int64
+	// Total is the total number of bytes of the body, and it's -1 if the size is unknown.
+	Total int64
+	// Rate is the average number of bytes that are transferred per second since the transfer
+	// started.
+	Rate float64
+}
+
+// progressInterval is the minimum interval between two calls of the progress function, except the
+// call for the completion of the transfer.
+const progressInterval = 100 * time.Millisecond
+
+// progressTracker counts the transferred bytes, and calls the progress function at most once per
+// progress interval.
+type progressTracker struct {
+	fn func(Progress)
+	// total is the total number of bytes, and it's -1 if the size is unknown.
+	total int64
+	// initial is the number of bytes that had been transferred before the tracker started, and
+	// they're not counted in the rate.
+	initial int64
+	// transferred is the number of bytes that have been transferred.
+	transferred int64
+	// reported is the number of transferred bytes of the last call of the progress function.
+	reported int64
+	// start is the time that the tracker started.
+	start time.Time
+	// last is the time of the last call of the progress function.
+	last time.Time
+	// mutex is the locker for the tracker, and the calls of the progress function are serialized.
+	mutex sync.Mutex
+}
+
+// newProgressTracker creates a tracker with the progress function, the total size, and the number
+// of the bytes that have been transferred.
+func newProgressTracker(fn func(Progress), total, transferred int64) *progressTracker {
+	return &progressTracker{
+		fn:          fn,
+		total:       total,
+		initial:     transferred,
+		transferred: transferred,
+		reported:    transferred,
+		start:       time.Now(),
+	}
+}
+
+// add adds the number of the transferred bytes, and calls the progress function if the interval
+// has elapsed or the transfer is completed.
+func (tracker *progressTracker) add(n int64) {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+
+	tracker.transferred += n
+	completed := tracker.total >= 0 && tracker.transferred >= tracker.total
+	if completed || time.Since(tracker.last) >= progressInterval {
+		tracker.report()
+	}
+}
+
+// finish calls the progress function with the final progress if it hasn't been reported.
+func (tracker *progressTracker) finish() {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+
+	if tracker.transferred != tracker.reported {
+		tracker.report()
+	}
+}
+
+// reset restarts the tracker with the total size and the number of the transferred bytes.
+func (tracker *progressTracker) reset(total, transferred int64) {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+
+	tracker.total = total
+	tracker.initial = transferred
+	tracker.transferred = transferred
+	tracker.reported = transferred
+	tracker.start = time.Now()
+	tracker.last = time.Time{}
+}
+
+// report calls the progress function with the current progress, and the caller must hold the lock.
+func (tracker *progressTracker) report() {
+	now := time.Now()
+	progress := Progress{
+		Transferred: tracker.transferred,
+		Total:       tracker.total,
+	}
+	if elapsed := now.Sub(tracker.start).Seconds(); elapsed > 0 {
+		progress.Rate = float64(tracker.transferred-tracker.initial) / elapsed
+	}
+
+	tracker.last = now
+	tracker.reported = tracker.transferred
+	tracker.fn(progress)
+}
+
+// progressBody is the body that reports the progress of reading.
+type progressBody struct {
+	io.ReadCloser
+	tracker *progressTracker
+}
+
+// Read reads the data from the underlying body, and adds the number of bytes to the tracker.
+func (body *progressBody) Read(p []byte) (int, error) {
+	n, err := body.ReadCloser.Read(p)
+	if n > 0 {
+		body.tracker.add(int64(n))
+	}
+	if err == io.EOF {
+		body.tracker.finish()
+	}
+
+	return n, err
+}
+
+// trackUploadProgress wraps the request body to report the progress of uploading if the upload
+// progress function is set.
+func trackUploadProgress(req *http.Request, opt RequestOptions) {
+	if opt.OnUploadProgress == nil || req.Body == nil || req.Body == http.NoBody {
+		return
+	}
+
+	total := req.ContentLength
+	if total <= 0 {
+		total = -1
+	}
+
+	req.Body = &progressBody{
+		ReadCloser: req.Body,
+		tracker:    newProgressTracker(opt.OnUploadProgress, total, 0),
+	}
+}
+
+// trackDownloadProgress wraps the response body to report the progress of downloading if the
+// download progress function is set.
+func trackDownloadProgress(resp *http.Response, opt RequestOptions) {
+	if opt.OnDownloadProgress == nil || resp.Body == nil || resp.Body == http.NoBody {
+		return
+	} else if _, ok := resp.Body.(io.Writer); ok {
+		// keep the writable body of the switching protocols response.
+		return
+	}
+
+	total := resp.ContentLength
+	if total < 0 {
+		total = -1
+	}
+
+	resp.Body = &progressBody{
+		ReadCloser: resp.Body,
+		tracker:    newProgressTracker(opt.OnDownloadProgress, total, 0),
+	}
+}
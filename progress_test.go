@@ -0,0 +1,138 @@
+package request
+
+import (
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ghosind/go-assert"
+)
+
+// progressRecorder records the progresses that are reported.
+type progressRecorder struct {
+	progresses []Progress
+	mutex      sync.Mutex
+}
+
+func (recorder *progressRecorder) record(progress Progress) {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	recorder.progresses = append(recorder.progresses, progress)
+}
+
+func (recorder *progressRecorder) last() Progress {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	if len(recorder.progresses) == 0 {
+		return Progress{}
+	}
+	return recorder.progresses[len(recorder.progresses)-1]
+}
+
+func TestDownloadProgress(t *testing.T) {
+	a := assert.New(t)
+
+	recorder := new(progressRecorder)
+	resp, err := GET("http://127.0.0.1:8080/download?size=1000000", RequestOptions{
+		OnDownloadProgress: recorder.record,
+	})
+	n, _, err := ToWriter(resp, err, io.Discard)
+	a.NilNow(err)
+	a.EqualNow(n, int64(1000000))
+
+	last := recorder.last()
+	a.EqualNow(last.Transferred, int64(1000000))
+	a.EqualNow(last.Total, int64(1000000))
+	a.TrueNow(last.Rate > 0)
+
+	// the size of the decompressed body is unknown
+	recorder = new(progressRecorder)
+	content, _, err := ToString(Req("http://127.0.0.1:8080/test").
+		AddHeader("Accept-Encoding", "gzip").
+		SetOnDownloadProgress(recorder.record).
+		Do())
+	a.NilNow(err)
+	last = recorder.last()
+	a.EqualNow(last.Transferred, int64(len(content)))
+	a.EqualNow(last.Total, int64(-1))
+}
+
+func TestUploadProgress(t *testing.T) {
+	a := assert.New(t)
+
+	recorder := new(progressRecorder)
+	body := strings.Repeat("a", 100000)
+	resp, err := Req("http://127.0.0.1:8080/test").
+		POST().
+		SetBody(body).
+		SetOnUploadProgress(recorder.record).
+		Do()
+	a.NilNow(err)
+	resp.Body.Close()
+
+	last := recorder.last()
+	a.EqualNow(last.Transferred, int64(100000))
+	a.EqualNow(last.Total, int64(100000))
+
+	// the size of the streaming multipart body is unknown
+	recorder = new(progressRecorder)
+	form := NewMultipartForm()
+	form.AddField("field", body)
+	resp, err = POST("http://127.0.0.1:8080/test", RequestOptions{
+		ContentType:      RequestContentTypeMultipart,
+		Multipart:        form,
+		OnUploadProgress: recorder.record,
+	})
+	a.NilNow(err)
+	resp.Body.Close()
+
+	last = recorder.last()
+	a.TrueNow(last.Transferred > 100000)
+	a.EqualNow(last.Total, int64(-1))
+
+	// no body to upload
+	recorder = new(progressRecorder)
+	resp, err = GET("http://127.0.0.1:8080/test", RequestOptions{
+		OnUploadProgress: recorder.record,
+	})
+	a.NilNow(err)
+	resp.Body.Close()
+	a.EqualNow(len(recorder.progresses), 0)
+}
+
+func TestProgressTrackerThrottle(t *testing.T) {
+	a := assert.New(t)
+
+	recorder := new(progressRecorder)
+	tracker := newProgressTracker(recorder.record, 1000, 0)
+	for i := 0; i < 99; i++ {
+		tracker.add(10)
+	}
+	a.EqualNow(len(recorder.progresses), 1)
+	a.EqualNow(recorder.last().Transferred, int64(10))
+
+	time.Sleep(progressInterval)
+	tracker.add(5)
+	a.EqualNow(len(recorder.progresses), 2)
+	a.EqualNow(recorder.last().Transferred, int64(995))
+
+	// always report the completion
+	tracker.add(5)
+	a.EqualNow(len(recorder.progresses), 3)
+	a.EqualNow(recorder.last().Transferred, int64(1000))
+	tracker.finish()
+	a.EqualNow(len(recorder.progresses), 3)
+
+	// report the last progress when finished
+	tracker.reset(-1, 0)
+	tracker.add(10)
+	tracker.add(10)
+	a.EqualNow(len(recorder.progresses), 4)
+	tracker.finish()
+	a.EqualNow(len(recorder.progresses), 5)
+	a.EqualNow(recorder.last(), Progress{Transferred: 20, Total: -1, Rate: recorder.last().Rate})
+}
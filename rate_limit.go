@@ -0,0 +1,242 @@
+package request
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RateLimitConfig defines the token-bucket rate limits of the outgoing requests. The requests
+// will wait for the tokens before sending, and they'll fail with an `ErrRateLimited` error if
+// they can't get the tokens before the deadline of the request context. Every attempt of the
+// requests, including retries, takes a token.
+//
+//	cli := request.New(request.Config{
+//	  RateLimit: &request.RateLimitConfig{
+//	    Rate:        100, // 100 requests per second for all hosts
+//	    Burst:       10,
+//	    PerHostRate: 5, // 5 requests per second for each host
+//	  },
+//	})
+type RateLimitConfig struct {
+	// Rate is the number of requests per second for all the requests of the client, no limit if
+	// it's less than or equal to 0.
+	Rate float64
+	// Burst is the maximum number of requests that can be sent at once for all the requests of
+	// the client, default 1.
+	Burst int
+	// PerHostRate is the number of requests per second for each host, no limit if it's less than
+	// or equal to 0.
+	PerHostRate float64
+	// PerHostBurst is the maximum number of requests that can be sent at once for each host,
+	// default 1.
+	PerHostBurst int
+}
+
+// RateLimitStats is the statistics of the rate limiter of a client.
+type RateLimitStats struct {
+	// Waiting is the number of the requests that are waiting for the tokens.
+	Waiting int64
+	// TotalWaits is the number of the requests that have waited for the tokens.
+	TotalWaits int64
+	// TotalWaitTime is the total time that the requests have waited for the tokens.
+	TotalWaitTime time.Duration
+	// Rejected is the number of the requests that failed because they can't get the tokens before
+	// the deadline or they're canceled while waiting.
+	Rejected int64
+}
+
+// rateLimiter is the token-bucket rate limiter of a client.
+type rateLimiter struct {
+	// config is the rate limits.
+	config RateLimitConfig
+	// global is the bucket for all the requests.
+	global *tokenBucket
+	// hosts are the buckets for the hosts.
+	hosts map[string]*tokenBucket
+	// mutex is the locker for the hosts.
+	mutex sync.Mutex
+
+	waiting       atomic.Int64
+	totalWaits    atomic.Int64
+	totalWaitTime atomic.Int64
+	rejected      atomic.Int64
+}
+
+// tokenBucket is a bucket that is refilled with the tokens at the rate, and the number of the
+// tokens can't exceed the burst. The number of the tokens will be negative if the tokens are
+// reserved in advance.
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	mutex  sync.Mutex
+}
+
+// newRateLimiter creates a rate limiter by the config, and it returns nil if the config has no
+// limit.
+func newRateLimiter(config *RateLimitConfig) *rateLimiter {
+	if config == nil || (config.Rate <= 0 && config.PerHostRate <= 0) {
+		return nil
+	}
+
+	limiter := &rateLimiter{
+		config: *config,
+		hosts:  make(map[string]*tokenBucket),
+	}
+	if config.Rate > 0 {
+		limiter.global = newTokenBucket(config.Rate, config.Burst)
+	}
+
+	return limiter
+}
+
+// newTokenBucket creates a full bucket with the rate and the burst.
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if burst <= 0 {
+		burst = 1
+	}
+
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// RateLimitStats returns the statistics of the client's rate limiter, and it returns the zero
+// value if no rate limit is set.
+func (cli *Client) RateLimitStats() RateLimitStats {
+	limiter := cli.rateLimiter
+	if limiter == nil {
+		return RateLimitStats{}
+	}
+
+	return RateLimitStats{
+		Waiting:       limiter.waiting.Load(),
+		TotalWaits:    limiter.totalWaits.Load(),
+		TotalWaitTime: time.Duration(limiter.totalWaitTime.Load()),
+		Rejected:      limiter.rejected.Load(),
+	}
+}
+
+// waitRateLimit waits for the tokens of the client's rate limiter before sending the request.
+func (cli *Client) waitRateLimit(req *http.Request) error {
+	if cli.rateLimiter == nil {
+		return nil
+	}
+
+	return cli.rateLimiter.wait(req.Context(), req.URL.Host)
+}
+
+// wait reserves the tokens from the global bucket and the host's bucket, and waits until the
+// tokens are available. The tokens will be returned if the request can't wait for them.
+func (limiter *rateLimiter) wait(ctx context.Context, host string) error {
+	buckets := make([]*tokenBucket, 0, 2)
+	if limiter.global != nil {
+		buckets = append(buckets, limiter.global)
+	}
+	if bucket := limiter.getHostBucket(host); bucket != nil {
+		buckets = append(buckets, bucket)
+	}
+
+	now := time.Now()
+	delay := time.Duration(0)
+	for _, bucket := range buckets {
+		if d := bucket.reserve(now); d > delay {
+			delay = d
+		}
+	}
+	if delay <= 0 {
+		return nil
+	}
+
+	if deadline, ok := ctx.Deadline(); ok && now.Add(delay).After(deadline) {
+		limiter.cancel(buckets)
+		return fmt.Errorf("%w: need to wait %v for %s", ErrRateLimited, delay, host)
+	}
+
+	limiter.waiting.Add(1)
+	limiter.totalWaits.Add(1)
+	defer limiter.waiting.Add(-1)
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		limiter.cancel(buckets)
+		limiter.totalWaitTime.Add(int64(time.Since(now)))
+		return ctx.Err()
+	case <-timer.C:
+		limiter.totalWaitTime.Add(int64(delay))
+		return nil
+	}
+}
+
+// cancel returns the reserved tokens to the buckets.
+func (limiter *rateLimiter) cancel(buckets []*tokenBucket) {
+	limiter.rejected.Add(1)
+
+	now := time.Now()
+	for _, bucket := range buckets {
+		bucket.cancel(now)
+	}
+}
+
+// getHostBucket returns the bucket of the host, and it returns nil if no per-host limit is set.
+func (limiter *rateLimiter) getHostBucket(host string) *tokenBucket {
+	if limiter.config.PerHostRate <= 0 {
+		return nil
+	}
+
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+
+	bucket, ok := limiter.hosts[host]
+	if !ok {
+		bucket = newTokenBucket(limiter.config.PerHostRate, limiter.config.PerHostBurst)
+		limiter.hosts[host] = bucket
+	}
+
+	return bucket
+}
+
+// reserve takes a token from the bucket, and returns the duration to wait until the token is
+// available.
+func (bucket *tokenBucket) reserve(now time.Time) time.Duration {
+	bucket.mutex.Lock()
+	defer bucket.mutex.Unlock()
+
+	bucket.advance(now)
+	bucket.tokens--
+	if bucket.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(math.Ceil(-bucket.tokens / bucket.rate * float64(time.Second)))
+}
+
+// cancel returns a reserved token to the bucket.
+func (bucket *tokenBucket) cancel(now time.Time) {
+	bucket.mutex.Lock()
+	defer bucket.mutex.Unlock()
+
+	bucket.advance(now)
+	bucket.tokens = math.Min(bucket.tokens+1, bucket.burst)
+}
+
+// advance refills the bucket with the tokens that are generated since the last update.
+func (bucket *tokenBucket) advance(now time.Time) {
+	if now.After(bucket.last) {
+		elapsed := now.Sub(bucket.last).Seconds()
+		bucket.tokens = math.Min(bucket.tokens+elapsed*bucket.rate, bucket.burst)
+		bucket.last = now
+	}
+}
@@ -0,0 +1,99 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ghosind/go-assert"
+)
+
+func TestRateLimit(t *testing.T) {
+	a := assert.New(t)
+
+	cli := New(Config{
+		RateLimit: &RateLimitConfig{Rate: 20, Burst: 2},
+	})
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		_, err := cli.GET("http://127.0.0.1:8080/test")
+		a.NilNow(err)
+	}
+	elapsed := time.Since(start)
+	a.TrueNow(elapsed >= 90*time.Millisecond)
+
+	stats := cli.RateLimitStats()
+	a.EqualNow(stats.TotalWaits, int64(2))
+	a.EqualNow(stats.Waiting, int64(0))
+	a.EqualNow(stats.Rejected, int64(0))
+	a.TrueNow(stats.TotalWaitTime > 0)
+
+	a.EqualNow(New().RateLimitStats(), RateLimitStats{})
+	a.NilNow(newRateLimiter(&RateLimitConfig{}))
+}
+
+func TestPerHostRateLimit(t *testing.T) {
+	a := assert.New(t)
+
+	cli := New(Config{
+		RateLimit: &RateLimitConfig{PerHostRate: 1},
+	})
+
+	_, err := cli.GET("http://127.0.0.1:8080/test")
+	a.NilNow(err)
+
+	// the other host has its own bucket
+	_, err = cli.GET("http://localhost:8080/test")
+	a.NilNow(err)
+
+	// the request can't get a token before the deadline
+	_, err = cli.GET("http://127.0.0.1:8080/test", RequestOptions{Timeout: 100})
+	a.NotNilNow(err)
+	a.TrueNow(errors.Is(err, ErrRateLimited))
+	a.EqualNow(cli.RateLimitStats().Rejected, int64(1))
+	a.EqualNow(cli.RateLimitStats().TotalWaits, int64(0))
+}
+
+func TestRateLimitCanceled(t *testing.T) {
+	a := assert.New(t)
+
+	cli := New(Config{
+		RateLimit: &RateLimitConfig{Rate: 1},
+	})
+
+	_, err := cli.GET("http://127.0.0.1:8080/test")
+	a.NilNow(err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	_, err = cli.GET("http://127.0.0.1:8080/test", RequestOptions{Context: ctx})
+	a.NotNilNow(err)
+	a.TrueNow(errors.Is(err, context.Canceled))
+
+	stats := cli.RateLimitStats()
+	a.EqualNow(stats.TotalWaits, int64(1))
+	a.EqualNow(stats.Rejected, int64(1))
+}
+
+func TestTokenBucket(t *testing.T) {
+	a := assert.New(t)
+
+	now := time.Now()
+	bucket := newTokenBucket(10, 2)
+	bucket.last = now
+
+	a.EqualNow(bucket.reserve(now), time.Duration(0))
+	a.EqualNow(bucket.reserve(now), time.Duration(0))
+	a.EqualNow(bucket.reserve(now), 100*time.Millisecond)
+	a.EqualNow(bucket.reserve(now), 200*time.Millisecond)
+
+	bucket.cancel(now)
+	a.EqualNow(bucket.reserve(now), 200*time.Millisecond)
+
+	// refill the bucket, and the tokens can't exceed the burst
+	a.EqualNow(bucket.reserve(now.Add(10*time.Second)), time.Duration(0))
+	a.EqualNow(bucket.tokens, float64(1))
+}
@@ -1,13 +1,7 @@
 package request
 
 import (
-	"bytes"
-	"compress/flate"
-	"compress/gzip"
 	"context"
-	"errors"
-	"fmt"
-	"io"
 	"net/http"
 	"net/url"
 	"path"
@@ -39,14 +33,15 @@ func (cli *Client) request(method, url string, opts ...RequestOptions) (*http.Re
 	if err != nil {
 		return nil, err
 	}
-	defer canFunc()
 
 	resp, err := cli.sendRequestWithInterceptors(req, opt)
-	if err != nil {
-		return resp, err
+	if err == nil {
+		resp, err = cli.handleResponse(resp, opt)
 	}
+	// keep the context until the body is closed, so the body can be read after returning.
+	callOnBodyClose(resp, canFunc)
 
-	return cli.handleResponse(resp, opt)
+	return resp, err
 }
 
 // sendRequestWithInterceptors tries to execute the request and response interceptors and
@@ -57,10 +52,11 @@ func (cli *Client) sendRequestWithInterceptors(
 ) (*http.Response, error) {
 	err := cli.doRequestIntercept(req)
 	if err != nil {
+		closeRequestBody(req.Body)
 		return nil, err
 	}
 
-	resp, err := cli.sendRequest(req, opt)
+	resp, err := cli.sendRequestWithCache(req, opt)
 	if err != nil {
 		return nil, err
 	}
@@ -74,30 +70,109 @@ func (cli *Client) sendRequestWithInterceptors(
 }
 
 // sendRequest gets an HTTP client from the HTTP clients pool and sends the request. It tries to
-// re-send the request when it fails to make the request and the number of attempts is less than
-// the maximum limitation.
+// re-send the request by the retry policy when the attempt is failed and the number of attempts
+// is less than the maximum limitation. The request body will be rebuilt for every attempt, and it
+// returns an `ErrBodyNotReplayable` error if the body can't be rebuilt. It also re-sends the
+// request once if the authenticator responds to the 401 challenge of the server. The request will
+// wait for the rate limiter and be signed by the signer before every sending, and it'll be
+// rejected if the circuit breaker is open or the bulkhead is full.
 func (cli *Client) sendRequest(req *http.Request, opt RequestOptions) (*http.Response, error) {
-	attempt := 0
-	maxAttempt := 1
-	if opt.MaxAttempt > 0 {
-		maxAttempt = opt.MaxAttempt
-	}
+	maxAttempt := cli.getMaxAttempt(opt)
+	policy := cli.getRetryPolicy(opt)
 
-	httpClient := cli.getHTTPClient(opt)
+	httpClient, err := cli.getHTTPClient(opt)
+	if err != nil {
+		closeRequestBody(req.Body)
+		return nil, err
+	}
 	defer func() {
 		cli.clientPool.Put(httpClient)
 	}()
 
-	for {
-		attempt++
+	challenged := false
+	for attempt := 1; ; attempt++ {
+		if err := cli.prepareSending(req, opt); err != nil {
+			closeRequestBody(req.Body)
+			return nil, err
+		}
 
-		resp, err := httpClient.Do(req)
-		if err == nil || attempt >= maxAttempt {
-			return resp, err
-		} else if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		resp, err := cli.doRequest(httpClient, req, opt)
+		if err == nil && !challenged {
+			challenged = true
+
+			resent, challengeErr := cli.respondAuthChallenge(req, resp, opt)
+			if challengeErr != nil {
+				return nil, challengeErr
+			} else if resent {
+				if err := cli.prepareSending(req, opt); err != nil {
+					closeRequestBody(req.Body)
+					return nil, err
+				}
+				resp, err = cli.doRequest(httpClient, req, opt)
+			}
+		}
+		if attempt >= maxAttempt || !policy.shouldRetry(resp, err) {
 			return resp, err
 		}
+
+		if !isRequestBodyReplayable(req) {
+			discardResponse(resp)
+			return nil, newBodyNotReplayableError(resp, err)
+		}
+
+		delay := policy.getDelay(attempt, resp)
+		if policy.OnRetry != nil {
+			policy.OnRetry(attempt, resp, err, delay)
+		}
+		discardResponse(resp)
+
+		if err := waitForRetry(req.Context(), delay); err != nil {
+			return nil, err
+		}
+
+		if err := rewindRequestBody(req); err != nil {
+			return nil, err
+		}
+
+		if err := cli.authenticate(req, opt); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// doRequest sends the request by the HTTP client through the bulkhead and the circuit breaker of
+// the client. The slots of the bulkhead will be released when the response body is closed.
+func (cli *Client) doRequest(
+	httpClient *http.Client,
+	req *http.Request,
+	opt RequestOptions,
+) (*http.Response, error) {
+	release, err := cli.acquireBulkhead(req)
+	if err != nil {
+		closeRequestBody(req.Body)
+		return nil, err
+	}
+
+	trackUploadProgress(req, opt)
+
+	resp, err := cli.doRequestWithCircuitBreaker(httpClient, req, opt)
+	if err != nil {
+		release()
+		return resp, err
 	}
+	callOnBodyClose(resp, release)
+
+	return resp, nil
+}
+
+// prepareSending waits for the tokens of the rate limiter and signs the request, it should be
+// called before every sending of the request.
+func (cli *Client) prepareSending(req *http.Request, opt RequestOptions) error {
+	if err := cli.waitRateLimit(req); err != nil {
+		return err
+	}
+
+	return cli.signRequest(req, opt)
 }
 
 // handleResponse handle the response that decompresses the body of the response if it was
@@ -109,61 +184,61 @@ func (cli *Client) handleResponse(
 	if !opt.DisableDecompress {
 		resp = cli.decodeResponseBody(resp)
 	}
+	trackDownloadProgress(resp, opt)
+	cli.limitResponseBody(resp, opt)
 
 	return cli.validateResponse(resp, opt)
 }
 
-// decodeResponseBody tries to get the encoding type of the response's content, and decode
-// (decompress) it if the response's body was compressed by `gzip` or `deflate`.
-func (cli *Client) decodeResponseBody(resp *http.Response) *http.Response {
-	switch resp.Header.Get("Content-Encoding") {
-	case "deflate":
-		data, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return resp
-		}
+// limitResponseBody limits the size of the response body by the max body size of the request
+// options or the client.
+func (cli *Client) limitResponseBody(resp *http.Response, opt RequestOptions) {
+	limit := cli.getMaxBodySize(opt)
+	if limit <= 0 || resp.Body == nil {
+		return
+	}
 
-		reader := flate.NewReader(bytes.NewReader(data))
-		resp.Body.Close()
-		resp.Body = reader
-		resp.Header.Del("Content-Encoding")
-	case "gzip", "x-gzip":
-		reader, err := gzip.NewReader(resp.Body)
-		if err != nil {
-			return resp
-		}
-		resp.Body.Close()
-		resp.Body = reader
-		resp.Header.Del("Content-Encoding")
+	resp.Body = &limitedBody{ReadCloser: resp.Body, limit: limit, remaining: limit}
+}
+
+// getMaxBodySize returns the max body size of the request options or the client, and it returns 0
+// if there is no limitation.
+func (cli *Client) getMaxBodySize(opt RequestOptions) int64 {
+	if opt.MaxBodySize > 0 {
+		return opt.MaxBodySize
 	}
 
-	return resp
+	return cli.MaxBodySize
 }
 
-// validateResponse validates the status code of the response, and returns fail if the result of
-// the validation is false.
+// validateResponse validates the status code of the response, and returns a `*ResponseError` if
+// the result of the validation is false.
 func (cli *Client) validateResponse(
 	resp *http.Response,
 	opt RequestOptions,
 ) (*http.Response, error) {
-	var validateStatus func(int) bool
-	if opt.ValidateStatus != nil {
-		validateStatus = opt.ValidateStatus
-	} else if cli.ValidateStatus != nil {
-		validateStatus = cli.ValidateStatus
-	} else {
-		validateStatus = cli.defaultValidateStatus
-	}
+	validateStatus := cli.getValidateStatus(opt)
 
-	status := resp.StatusCode
-	ok := validateStatus(status)
+	ok := validateStatus(resp.StatusCode)
 	if !ok {
-		return resp, fmt.Errorf("request failed with status code %d", status)
+		return resp, newResponseError(resp)
 	}
 
 	return resp, nil
 }
 
+// getValidateStatus returns the function to validate the status code of the responses from the
+// request options or the client config.
+func (cli *Client) getValidateStatus(opt RequestOptions) func(int) bool {
+	if opt.ValidateStatus != nil {
+		return opt.ValidateStatus
+	} else if cli.ValidateStatus != nil {
+		return cli.ValidateStatus
+	}
+
+	return cli.defaultValidateStatus
+}
+
 // makeRequest creates a new `http.Request` object with the specific HTTP method, request url, and
 // other configurations.
 func (cli *Client) makeRequest(
@@ -180,21 +255,29 @@ func (cli *Client) makeRequest(
 		return nil, nil, err
 	}
 
+	if strings.ToLower(opt.ContentType) == RequestContentTypeMultipart && opt.Multipart == nil {
+		// make sure the body and the header use the same boundary.
+		opt.Multipart = NewMultipartForm()
+	}
+
 	body, err := cli.getRequestBody(opt)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	ctx, canFunc := cli.getContext(opt)
+	ctx = cli.withClientContext(ctx)
 
 	req, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
 		canFunc()
+		closeRequestBody(body)
 		return nil, nil, err
 	}
 
 	if err := cli.attachRequestHeaders(req, opt); err != nil {
 		canFunc()
+		closeRequestBody(body)
 		return nil, nil, err
 	}
 
@@ -223,18 +306,16 @@ func (cli *Client) getRequestMethod(method string) (string, error) {
 // request headers by the config.
 func (cli *Client) attachRequestHeaders(req *http.Request, opt RequestOptions) error {
 	cli.setHeaders(req, opt)
+	cli.setAcceptEncoding(req, opt)
 
 	if err := cli.setContentType(req, opt); err != nil {
 		return err
 	}
 
 	cli.setUserAgent(req, opt)
+	setCookies(req, opt)
 
-	if opt.Auth != nil {
-		req.SetBasicAuth(opt.Auth.Username, opt.Auth.Password)
-	}
-
-	return nil
+	return cli.authenticate(req, opt)
 }
 
 // setHeaders set the field values of the request headers from the request options or the client
@@ -263,18 +344,30 @@ func (cli *Client) setHeaders(req *http.Request, opt RequestOptions) {
 }
 
 // setContentType checks the "Content-Type" field in the request headers, and set it by the
-// "ContentType" field value from the request options if no value is set in the headers.
+// "ContentType" field value from the request options if no value is set in the headers. The value
+// will be the first MIME type of the codec with the same name as the content type.
 func (cli *Client) setContentType(req *http.Request, opt RequestOptions) error {
 	contentType := req.Header.Get("Content-Type")
 	if contentType != "" {
 		return nil
 	}
 
-	switch strings.ToLower(opt.ContentType) {
-	case RequestContentTypeJSON, "":
-		contentType = "application/json"
+	switch name := strings.ToLower(opt.ContentType); name {
+	case RequestContentTypeMultipart:
+		contentType = "multipart/form-data"
+		if opt.Multipart != nil {
+			contentType = opt.Multipart.FormDataContentType()
+		}
 	default:
-		return ErrUnsupportedType
+		if name == "" {
+			name = RequestContentTypeJSON
+		}
+
+		codec, ok := cli.getCodec(name)
+		if !ok || len(codec.MIMETypes()) == 0 {
+			return ErrUnsupportedType
+		}
+		contentType = codec.MIMETypes()[0]
 	}
 
 	req.Header.Set("Content-Type", contentType)
@@ -386,8 +479,15 @@ func (cli *Client) getContext(opt RequestOptions) (context.Context, context.Canc
 		return opt.Context, func() {} // empty cancel function, just do nothing
 	}
 
-	baseCtx := context.Background()
+	return cli.withTimeout(context.Background(), opt)
+}
 
+// withTimeout creates a Context from the parent with the timeout of the request options or client
+// settings.
+func (cli *Client) withTimeout(
+	baseCtx context.Context,
+	opt RequestOptions,
+) (context.Context, context.CancelFunc) {
 	timeout := RequestTimeoutDefault
 	if opt.Timeout > 0 || opt.Timeout == RequestTimeoutNoLimit {
 		timeout = opt.Timeout
@@ -2,7 +2,9 @@ package request
 
 import (
 	"context"
+	"io"
 	"net/http"
+	"net/textproto"
 )
 
 // RequestOptions is the config for a request.
@@ -17,6 +19,17 @@ type RequestOptions struct {
 	//	  },
 	//	})
 	Auth *BasicAuthConfig
+	// Authenticator adds the credentials to the request, and it takes precedence over the `Auth`
+	// field and the client's authenticator. The package provides `*BasicAuthConfig`,
+	// `*BearerAuth`, `*APIKeyAuth`, and `*DigestAuth` authenticators.
+	//
+	//	resp, err := request.Request("https://example.com", request.RequestOptions{
+	//	  Authenticator: &request.DigestAuth{
+	//	    Username: "user",
+	//	    Password: "pass",
+	//	  },
+	//	})
+	Authenticator Authenticator
 	// BaseURL will prepended to the url of the request unless the url is absolute.
 	//
 	//	resp, err := request.Request("/test", request.RequestOptions{
@@ -43,13 +56,32 @@ type RequestOptions struct {
 	//	})
 	Body any
 	// ContentType indicates the type of data that will encode and send to the server. Available
-	// options are: "json", default "json".
+	// options are: "json", "form", "multipart", and "xml", default "json".
 	//
 	//	request.POST("http://example.com", request.RequestOptions{
 	//	  ContentType: request.RequestContentTypeJSON, // "json"
 	//	  // ...
 	//	})
+	//
+	//	request.POST("http://example.com", request.RequestOptions{
+	//	  ContentType: request.RequestContentTypeForm, // "form"
+	//	  Body: map[string]string{
+	//	    "grant_type": "client_credentials",
+	//	  },
+	//	})
+	//
+	// For the "multipart" content type, the parts in the `Multipart` field will be sent as a
+	// `multipart/form-data` body, and the `Body` field will be encoded as the text fields.
 	ContentType string
+	// Cookies are the cookies to be sent with the request, in addition to the cookies from the
+	// client's cookie jar.
+	//
+	//	resp, err := request.Request("http://example.com", request.RequestOptions{
+	//	  Cookies: []*http.Cookie{
+	//	    {Name: "session", Value: "XXXXX"},
+	//	  },
+	//	})
+	Cookies []*http.Cookie
 	// Context is a `context.Content` object that is used for manipulating the request by yourself.
 	// The `Timeout` field will be ignored if this value is not empty, and you need to control
 	// timeout by yourself.
@@ -60,6 +92,9 @@ type RequestOptions struct {
 	//	  Context: ctx,
 	//	})
 	Context context.Context
+	// DisableCache indicates whether to bypass the client's cache for the request, the request will
+	// always be sent to the server and the response will not be cached.
+	DisableCache bool
 	// DisableDecompress indicates whether or not disable decompression of the response body
 	// automatically. If it is set to `true`, it will not decompress the response body.
 	DisableDecompress bool
@@ -71,19 +106,61 @@ type RequestOptions struct {
 	//	  },
 	//	})
 	Headers map[string][]string
-	// MaxAttempt defines the maximum number of attempts to request, default no retry.
+	// HedgePolicy defines when to send the hedged attempts if the request hasn't responded, and it
+	// will overwrite the client's hedge policy. It only works for the requests with the safe
+	// methods, like GET and HEAD.
+	//
+	//	resp, err := request.GET("http://example.com", request.RequestOptions{
+	//	  HedgePolicy: &request.HedgePolicy{
+	//	    Delay: 50 * time.Millisecond,
+	//	  },
+	//	})
+	HedgePolicy *HedgePolicy
+	// MaxAttempt defines the maximum number of attempts to request, it will overwrite the client's
+	// max attempt, default no retry.
 	//
 	// It will always reuse the same context for the same request when retrying, and it may fail if
-	// the context is canceled or timeout.
+	// the context is canceled or timeout. The request body will be sent again for every attempt,
+	// and it'll fail with an `ErrBodyNotReplayable` error if the body is a stream that can't be
+	// read again, like a multipart form.
 	MaxAttempt int
+	// MaxBodySize is the maximum number of bytes of the response body after decompression, and it
+	// will overwrite the client's max body size. Reading the body fails with a
+	// `*BodyTooLargeError` error if the body is larger than the limit. No limitation if it's 0.
+	MaxBodySize int64
 	// MaxRedirects defines the maximum number of redirects, default 5.
 	MaxRedirects int
+	// Multipart is the multipart form to be sent as the request body if the value of the
+	// `ContentType` field is "multipart". The parts will be streamed to the server without
+	// buffering the whole body in memory.
+	//
+	//	form := request.NewMultipartForm()
+	//	form.AddFile("file", "report.csv", file)
+	//	resp, err := request.POST("http://example.com/upload", request.RequestOptions{
+	//	  ContentType: request.RequestContentTypeMultipart,
+	//	  Multipart:   form,
+	//	})
+	Multipart *MultipartForm
 	// Method indicates the HTTP method of the request, default GET.
 	//
 	//	request.Request("http://example.com", request.RequestOptions{
 	//	  Method: http.MethodPost, // "POST"
 	//	})
 	Method string
+	// OnDownloadProgress is the function to receive the progress of reading the response body,
+	// and it's called at most every 100 milliseconds and once more when the body is read
+	// completely. The total size is the size of the decompressed body, and it's -1 if unknown.
+	//
+	//	request.Request("http://example.com/file", request.RequestOptions{
+	//	  OnDownloadProgress: func(progress request.Progress) {
+	//	    fmt.Printf("%d/%d\n", progress.Transferred, progress.Total)
+	//	  },
+	//	})
+	OnDownloadProgress func(Progress)
+	// OnUploadProgress is the function to receive the progress of sending the request body, and
+	// it's called at most every 100 milliseconds and once more when the body is sent completely.
+	// The progress restarts for every attempt of the request.
+	OnUploadProgress func(Progress)
 	// Parameters are the URL parameters to be sent with the request.
 	//
 	//	resp, err := request.Request("http://example.com", request.RequestOptions{
@@ -100,19 +177,54 @@ type RequestOptions struct {
 	// environment variables. If no proxy config in the request options or the client config, the
 	// request will try to get a proxy from the environment variables.
 	Proxy *ProxyConfig
+	// RetryPolicy defines when and how long to wait before re-sending a failed request, it will
+	// overwrite the client's retry policy.
+	//
+	//	resp, err := request.Request("http://example.com", request.RequestOptions{
+	//	  MaxAttempt: 3,
+	//	  RetryPolicy: &request.RetryPolicy{
+	//	    BaseDelay:   100 * time.Millisecond,
+	//	    ShouldRetry: request.RetryOnStatus(http.StatusServiceUnavailable),
+	//	  },
+	//	})
+	RetryPolicy *RetryPolicy
+	// Signer signs the request after the request interceptors, when the headers and the body of
+	// the request are final. It will overwrite the client's signer.
+	//
+	//	resp, err := request.POST("http://example.com", request.RequestOptions{
+	//	  Body: data,
+	//	  Signer: &request.HMACSigner{
+	//	    Key:           []byte("secret"),
+	//	    SignedHeaders: []string{"Host", "Content-Type"},
+	//	  },
+	//	})
+	Signer Signer
 	// InsecureSkipVerify controls whether the HTTP client verifies the server's certificate and host
 	// name.
 	InsecureSkipVerify bool
-	// Timeout specifies the number of milliseconds before the request times out. This value will be
-	// ignored if the `Content` field in the request options is set. It indicates no time-out
-	// limitation if the value is -1.
+	// TLS defines the TLS settings for the request, like the custom root CAs, the client
+	// certificates for mutual TLS, and the pinned public keys. It will overwrite the client's TLS
+	// config.
+	//
+	//	resp, err := request.Request("https://example.com", request.RequestOptions{
+	//	  TLS: &request.TLSConfig{
+	//	    RootCAs: [][]byte{caPEM},
+	//	    Certificates: []request.TLSCertificate{
+	//	      {CertPEM: certPEM, KeyPEM: keyPEM},
+	//	    },
+	//	  },
+	//	})
+	TLS *TLSConfig
+	// Timeout specifies the number of milliseconds before the request times out, including reading
+	// the response body. This value will be ignored if the `Content` field in the request options
+	// is set. It indicates no time-out limitation if the value is -1.
 	Timeout int
 	// UserAgent sets the client's User-Agent field in the request header. It'll overwrite the value
 	// of the `User-Agent` field in the request headers.
 	UserAgent string
 	// ValidateStatus defines whether the status code of the response is valid or not, and it'll
-	// return an error if fails to validate the status code. Default, it sets the result to fail if
-	// the status code is less than 200, or greater than and equal to 400.
+	// return a `*ResponseError` if fails to validate the status code. Default, it sets the result
+	// to fail if the status code is less than 200, or greater than and equal to 400.
 	//
 	//	resp, err := request.Request("http://example.com", request.RequestOptions{
 	//	  ValidateStatus: func (status int) bool {
@@ -168,6 +280,28 @@ func (opt *RequestOptions) SetBasicAuth(username, password string) *RequestOptio
 	return opt
 }
 
+// SetAuthenticator sets the authenticator of the request.
+//
+//	request.Req("http://example.com").
+//	  SetAuthenticator(&request.APIKeyAuth{Name: "X-API-Key", Value: "XXXXX"}).
+//	  Do()
+func (opt *RequestOptions) SetAuthenticator(auth Authenticator) *RequestOptions {
+	opt.Authenticator = auth
+
+	return opt
+}
+
+// SetBearerToken sets the token as the bearer token of the request.
+//
+//	request.Req("http://example.com").
+//	  SetBearerToken("XXXXX").
+//	  Do()
+func (opt *RequestOptions) SetBearerToken(token string) *RequestOptions {
+	opt.Authenticator = &BearerAuth{Token: token}
+
+	return opt
+}
+
 // SetBaseURL sets the base URL of the request.
 //
 //	request.Req("/test").
@@ -205,6 +339,17 @@ func (opt *RequestOptions) SetContentType(contentType string) *RequestOptions {
 	return opt
 }
 
+// AddCookie adds the cookies to the request.
+//
+//	request.Req("http://example.com").
+//	  AddCookie(&http.Cookie{Name: "session", Value: "XXXXX"}).
+//	  Do()
+func (opt *RequestOptions) AddCookie(cookies ...*http.Cookie) *RequestOptions {
+	opt.Cookies = append(opt.Cookies, cookies...)
+
+	return opt
+}
+
 // SetContext sets the context of the request, and it will skip the value of the `Timeout` field in
 // the request options if context is not empty.
 //
@@ -220,6 +365,13 @@ func (opt *RequestOptions) SetContext(ctx context.Context) *RequestOptions {
 	return opt
 }
 
+// SetDisableCache sets whether to bypass the client's cache for the request.
+func (opt *RequestOptions) SetDisableCache(isDisable bool) *RequestOptions {
+	opt.DisableCache = isDisable
+
+	return opt
+}
+
 // SetDisableDecompress sets whether to decompress the response body or not, and sets true to
 // disable automatic decompression.
 //
@@ -284,6 +436,20 @@ func (opt *RequestOptions) SetHeaders(headers map[string][]string) *RequestOptio
 	return opt
 }
 
+// SetHedgePolicy sets the policy of sending the hedged attempts.
+//
+//	request.Req("http://example.com").
+//	  SetHedgePolicy(request.HedgePolicy{
+//	    Delay:       50 * time.Millisecond,
+//	    MaxAttempts: 3,
+//	  }).
+//	  Do()
+func (opt *RequestOptions) SetHedgePolicy(policy HedgePolicy) *RequestOptions {
+	opt.HedgePolicy = &policy
+
+	return opt
+}
+
 // SetAttempt sets the maximum number of attempts to request.
 func (opt *RequestOptions) SetAttempt(maxAttempt int) *RequestOptions {
 	opt.MaxAttempt = maxAttempt
@@ -291,6 +457,17 @@ func (opt *RequestOptions) SetAttempt(maxAttempt int) *RequestOptions {
 	return opt
 }
 
+// SetMaxBodySize sets the maximum number of bytes of the response body.
+//
+//	Req("http://example.com").
+//	  SetMaxBodySize(10 << 20). // 10 MB
+//	  Do()
+func (opt *RequestOptions) SetMaxBodySize(size int64) *RequestOptions {
+	opt.MaxBodySize = size
+
+	return opt
+}
+
 // SetMaxRedirects sets the maximum number of redirects for the request.
 //
 //	Req("http://example.com").
@@ -314,6 +491,20 @@ func (opt *RequestOptions) SetMethod(method string) *RequestOptions {
 	return opt
 }
 
+// SetOnDownloadProgress sets the function to receive the progress of reading the response body.
+func (opt *RequestOptions) SetOnDownloadProgress(fn func(Progress)) *RequestOptions {
+	opt.OnDownloadProgress = fn
+
+	return opt
+}
+
+// SetOnUploadProgress sets the function to receive the progress of sending the request body.
+func (opt *RequestOptions) SetOnUploadProgress(fn func(Progress)) *RequestOptions {
+	opt.OnUploadProgress = fn
+
+	return opt
+}
+
 // DELETE sets the HTTP method of the request to `DELETE`.
 //
 //	Req("http://example.com").
@@ -398,6 +589,70 @@ func (opt *RequestOptions) PUT() *RequestOptions {
 	return opt
 }
 
+// AddFormField adds a text field to the multipart form of the request, and it'll set the content
+// type of the request to "multipart" if no content type is set.
+//
+//	request.Req("http://example.com/upload").
+//	  POST().
+//	  AddFormField("name", "avatar").
+//	  AddFile("file", "avatar.png", file).
+//	  Do()
+func (opt *RequestOptions) AddFormField(name, value string) *RequestOptions {
+	opt.getMultipartForm().AddField(name, value)
+
+	return opt
+}
+
+// AddFile adds a file to the multipart form of the request, and it'll set the content type of the
+// request to "multipart" if no content type is set. The content will be streamed to the server,
+// and it'll not be closed after the request is sent.
+//
+//	file, _ := os.Open("avatar.png")
+//	defer file.Close()
+//
+//	request.Req("http://example.com/upload").
+//	  POST().
+//	  AddFile("file", "avatar.png", file).
+//	  Do()
+func (opt *RequestOptions) AddFile(field, filename string, content io.Reader) *RequestOptions {
+	opt.getMultipartForm().AddFile(field, filename, content)
+
+	return opt
+}
+
+// AddPart adds a part with the custom MIME header to the multipart form of the request, and it'll
+// set the content type of the request to "multipart" if no content type is set.
+//
+//	header := make(textproto.MIMEHeader)
+//	header.Set("Content-Disposition", `form-data; name="metadata"`)
+//	header.Set("Content-Type", "application/json")
+//
+//	request.Req("http://example.com/upload").
+//	  POST().
+//	  AddPart(header, strings.NewReader(`{"name":"avatar"}`)).
+//	  Do()
+func (opt *RequestOptions) AddPart(
+	header textproto.MIMEHeader,
+	content io.Reader,
+) *RequestOptions {
+	opt.getMultipartForm().AddPart(header, content)
+
+	return opt
+}
+
+// getMultipartForm returns the multipart form of the request options, and creates a new one if
+// it's not set.
+func (opt *RequestOptions) getMultipartForm() *MultipartForm {
+	if opt.Multipart == nil {
+		opt.Multipart = NewMultipartForm()
+	}
+	if opt.ContentType == "" {
+		opt.ContentType = RequestContentTypeMultipart
+	}
+
+	return opt.Multipart
+}
+
 // SetParameter add the value to the query parameter with the specified key.
 //
 //	request.Req("http://example.com").
@@ -473,6 +728,34 @@ func (opt *RequestOptions) SetProxy(proxy ProxyConfig) *RequestOptions {
 	return opt
 }
 
+// SetRetryPolicy sets the policy of retrying the failed requests.
+//
+//	request.Req("http://example.com").
+//	  SetAttempt(3).
+//	  SetRetryPolicy(request.RetryPolicy{
+//	    BaseDelay:   100 * time.Millisecond,
+//	    ShouldRetry: request.RetryOnServerErrors,
+//	  }).
+//	  Do()
+func (opt *RequestOptions) SetRetryPolicy(policy RetryPolicy) *RequestOptions {
+	opt.RetryPolicy = &policy
+
+	return opt
+}
+
+// SetSigner sets the signer of the request.
+//
+//	request.Req("http://example.com").
+//	  POST().
+//	  SetBody(data).
+//	  SetSigner(&request.HMACSigner{Key: []byte("secret")}).
+//	  Do()
+func (opt *RequestOptions) SetSigner(signer Signer) *RequestOptions {
+	opt.Signer = signer
+
+	return opt
+}
+
 // SetInsecureSkipVerify sets and controls whether the HTTP client verifies the server's
 // certificate and host name.
 func (opt *RequestOptions) SetInsecureSkipVerify(skipVerify bool) *RequestOptions {
@@ -481,6 +764,20 @@ func (opt *RequestOptions) SetInsecureSkipVerify(skipVerify bool) *RequestOption
 	return opt
 }
 
+// SetTLSConfig sets the TLS settings for the request, and it will overwrite the client's TLS
+// config.
+//
+//	request.Req("https://example.com").
+//	  SetTLSConfig(request.TLSConfig{
+//	    RootCAFiles: []string{"ca.pem"},
+//	  }).
+//	  Do()
+func (opt *RequestOptions) SetTLSConfig(cfg TLSConfig) *RequestOptions {
+	opt.TLS = &cfg
+
+	return opt
+}
+
 // SetTimeout sets the timeout of the request in the milliseconds.
 //
 //	request.Req("http://example.com").
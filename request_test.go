@@ -159,6 +159,20 @@ func TestSetContentType(t *testing.T) {
 	a.Equal(req.Header.Get("Content-Type"), "application/json")
 	req.Header.Del("Content-Type")
 
+	err = cli.setContentType(req, RequestOptions{
+		ContentType: "form",
+	})
+	a.NilNow(err)
+	a.Equal(req.Header.Get("Content-Type"), "application/x-www-form-urlencoded")
+	req.Header.Del("Content-Type")
+
+	err = cli.setContentType(req, RequestOptions{
+		ContentType: "xml",
+	})
+	a.NilNow(err)
+	a.Equal(req.Header.Get("Content-Type"), "application/xml")
+	req.Header.Del("Content-Type")
+
 	req.Header.Set("Content-Type", "application/vnd.github+json")
 	err = cli.setContentType(req, RequestOptions{
 		ContentType: "json",
@@ -0,0 +1,265 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryPolicy defines when and how long to wait before re-sending a failed request. The number
+// of attempts is defined by the `MaxAttempt` field in the request options or the client config.
+//
+//	cli := request.New(request.Config{
+//	  MaxAttempt: 3,
+//	  RetryPolicy: &request.RetryPolicy{
+//	    BaseDelay:   100 * time.Millisecond,
+//	    MaxDelay:    2 * time.Second,
+//	    Jitter:      0.2,
+//	    ShouldRetry: request.RetryOnServerErrors,
+//	  },
+//	})
+type RetryPolicy struct {
+	// BaseDelay is the delay before the first retry, and the delay of the following retries will
+	// be increased exponentially by the multiplier. It'll retry immediately if it's 0.
+	BaseDelay time.Duration
+	// MaxDelay is the maximum delay before a retry, including the delay that is specified by the
+	// `Retry-After` field in the response headers. No limitation if it's 0.
+	MaxDelay time.Duration
+	// Multiplier is the factor that the delay is multiplied by for each retry, default 2.
+	Multiplier float64
+	// Jitter is the randomization factor of the delay in the range [0, 1]. For example, the delay
+	// will be a random value between 80ms and 120ms if the delay is 100ms and the jitter is 0.2.
+	Jitter float64
+	// IgnoreRetryAfter indicates whether to ignore the `Retry-After` field in the response headers,
+	// it'll wait for the time that is specified by the `Retry-After` field before retrying if it's
+	// greater than the calculated delay by default.
+	IgnoreRetryAfter bool
+	// ShouldRetry decides whether to retry the request by the response and the error of the last
+	// attempt. It only retries when it fails to send the request if no function is set. It'll
+	// never retry if the context of the request is canceled or timeout, or the circuit is open,
+	// or the bulkhead is full.
+	ShouldRetry func(resp *http.Response, err error) bool
+	// OnRetry is a hook that is called before waiting for the next attempt, with the number of the
+	// failed attempt, the response and the error of the attempt, and the delay before the next
+	// attempt. The body of the response will be discarded after the hook is called.
+	OnRetry func(attempt int, resp *http.Response, err error, delay time.Duration)
+}
+
+// defaultRetryPolicy is the retry policy for the requests that no retry policy is set, and it
+// retries immediately when it fails to send the request.
+var defaultRetryPolicy = &RetryPolicy{}
+
+// DefaultShouldRetry returns true if it fails to send the request, and it's the default condition
+// of retrying requests.
+func DefaultShouldRetry(resp *http.Response, err error) bool {
+	return err != nil
+}
+
+// RetryOnServerErrors returns true if it fails to send the request, or the status code of the
+// response is 429 (Too Many Requests) or 5XX except 501 (Not Implemented).
+func RetryOnServerErrors(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+
+	return resp.StatusCode == http.StatusTooManyRequests ||
+		(resp.StatusCode >= http.StatusInternalServerError &&
+			resp.StatusCode != http.StatusNotImplemented)
+}
+
+// RetryOnStatus returns a function for the `ShouldRetry` field of the retry policy, and the
+// function returns true if it fails to send the request, or the status code of the response is
+// one of the specified status codes.
+//
+//	policy := &request.RetryPolicy{
+//	  ShouldRetry: request.RetryOnStatus(http.StatusServiceUnavailable),
+//	}
+func RetryOnStatus(statuses ...int) func(*http.Response, error) bool {
+	return func(resp *http.Response, err error) bool {
+		if err != nil {
+			return true
+		}
+
+		for _, status := range statuses {
+			if resp.StatusCode == status {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// getMaxAttempt returns the maximum number of attempts from the request options or the client
+// config, default 1.
+func (cli *Client) getMaxAttempt(opt RequestOptions) int {
+	if opt.MaxAttempt > 0 {
+		return opt.MaxAttempt
+	} else if cli.MaxAttempt > 0 {
+		return cli.MaxAttempt
+	}
+
+	return 1
+}
+
+// getRetryPolicy returns the retry policy from the request options or the client config.
+func (cli *Client) getRetryPolicy(opt RequestOptions) *RetryPolicy {
+	if opt.RetryPolicy != nil {
+		return opt.RetryPolicy
+	} else if cli.RetryPolicy != nil {
+		return cli.RetryPolicy
+	}
+
+	return defaultRetryPolicy
+}
+
+// shouldRetry checks whether to retry the request by the response and the error of the last
+// attempt.
+func (policy *RetryPolicy) shouldRetry(resp *http.Response, err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, ErrCircuitOpen) || errors.Is(err, ErrBulkheadFull) {
+		return false
+	}
+
+	shouldRetry := policy.ShouldRetry
+	if shouldRetry == nil {
+		shouldRetry = DefaultShouldRetry
+	}
+
+	return shouldRetry(resp, err)
+}
+
+// getDelay calculates the delay before the next attempt by the number of the failed attempt and
+// the response of it.
+func (policy *RetryPolicy) getDelay(attempt int, resp *http.Response) time.Duration {
+	delay := time.Duration(0)
+
+	if policy.BaseDelay > 0 {
+		multiplier := policy.Multiplier
+		if multiplier <= 0 {
+			multiplier = 2
+		}
+
+		backoff := float64(policy.BaseDelay) * math.Pow(multiplier, float64(attempt-1))
+		if policy.Jitter > 0 {
+			jitter := math.Min(policy.Jitter, 1)
+			backoff *= 1 + jitter*(2*rand.Float64()-1)
+		}
+
+		if backoff >= math.MaxInt64 {
+			delay = time.Duration(math.MaxInt64)
+		} else {
+			delay = time.Duration(backoff)
+		}
+	}
+
+	if !policy.IgnoreRetryAfter && resp != nil {
+		retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After"))
+		if ok && retryAfter > delay {
+			delay = retryAfter
+		}
+	}
+
+	if policy.MaxDelay > 0 && delay > policy.MaxDelay {
+		delay = policy.MaxDelay
+	}
+
+	return delay
+}
+
+// parseRetryAfter parses the value of the `Retry-After` field, it can be a number of seconds or
+// an HTTP date.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		delay := time.Until(date)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+
+	return 0, false
+}
+
+// waitForRetry waits for the delay, and it returns the error of the context if the context is
+// done before the delay.
+func waitForRetry(ctx context.Context, delay time.Duration) error {
+	if delay <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// isRequestBodyReplayable checks whether the body of the request can be read again for the next
+// attempt.
+func isRequestBodyReplayable(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// rewindRequestBody resets the body of the request by the `GetBody` function of the request, to
+// make sure the next attempt sends the whole payload.
+func rewindRequestBody(req *http.Request) error {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil
+	} else if req.GetBody == nil {
+		return ErrBodyNotReplayable
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return err
+	}
+	req.Body = body
+
+	return nil
+}
+
+// newBodyNotReplayableError creates an `ErrBodyNotReplayable` error with the result of the last
+// attempt.
+func newBodyNotReplayableError(resp *http.Response, err error) error {
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrBodyNotReplayable, err)
+	}
+
+	return fmt.Errorf("%w: last attempt responded with status code %d", ErrBodyNotReplayable,
+		resp.StatusCode)
+}
+
+// discardResponse reads a part of the response body and closes it, to make the connection
+// reusable.
+func discardResponse(resp *http.Response) {
+	if resp == nil || resp.Body == nil {
+		return
+	}
+
+	io.CopyN(io.Discard, resp.Body, 4096)
+	resp.Body.Close()
+}
@@ -0,0 +1,184 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ghosind/go-assert"
+)
+
+const retryURL = "http://localhost:8080/retry"
+
+func TestRetryOnStatus(t *testing.T) {
+	a := assert.New(t)
+
+	attempts := make([]int, 0)
+	resp, err := Request(retryURL+"?key=retry-on-status&failures=2", RequestOptions{
+		MaxAttempt: 3,
+		RetryPolicy: &RetryPolicy{
+			BaseDelay:   10 * time.Millisecond,
+			ShouldRetry: RetryOnStatus(http.StatusServiceUnavailable),
+			OnRetry: func(attempt int, resp *http.Response, err error, delay time.Duration) {
+				a.NilNow(err)
+				a.EqualNow(resp.StatusCode, http.StatusServiceUnavailable)
+				attempts = append(attempts, attempt)
+			},
+		},
+	})
+	a.NilNow(err)
+	a.EqualNow(resp.StatusCode, http.StatusOK)
+	a.EqualNow(resp.Header.Get("X-Attempt"), "3")
+	a.EqualNow(attempts, []int{1, 2})
+
+	resp, err = Request(retryURL+"?key=retry-exhausted&failures=3", RequestOptions{
+		MaxAttempt: 2,
+		RetryPolicy: &RetryPolicy{
+			ShouldRetry: RetryOnServerErrors,
+		},
+	})
+	a.NotNilNow(err)
+	a.EqualNow(resp.StatusCode, http.StatusServiceUnavailable)
+	a.EqualNow(resp.Header.Get("X-Attempt"), "2")
+
+	// no retry on status without the retry condition
+	resp, err = Request(retryURL+"?key=retry-default&failures=1", RequestOptions{
+		MaxAttempt: 3,
+	})
+	a.NotNilNow(err)
+	a.EqualNow(resp.Header.Get("X-Attempt"), "1")
+}
+
+func TestClientRetryPolicy(t *testing.T) {
+	a := assert.New(t)
+
+	cli := New(Config{
+		MaxAttempt: 2,
+		RetryPolicy: &RetryPolicy{
+			ShouldRetry: RetryOnServerErrors,
+		},
+	})
+
+	resp, err := cli.GET(retryURL + "?key=client-retry&failures=1&status=429")
+	a.NilNow(err)
+	a.EqualNow(resp.Header.Get("X-Attempt"), "2")
+
+	// overwritten by the request options
+	resp, err = cli.GET(retryURL+"?key=client-retry-opt&failures=1", RequestOptions{
+		MaxAttempt: 1,
+	})
+	a.NotNilNow(err)
+	a.EqualNow(resp.Header.Get("X-Attempt"), "1")
+}
+
+func TestRetryWithRetryAfter(t *testing.T) {
+	a := assert.New(t)
+
+	start := time.Now()
+	resp, err := Request(retryURL+"?key=retry-after&failures=1&retryAfter=1", RequestOptions{
+		MaxAttempt: 2,
+		Timeout:    3000,
+		RetryPolicy: &RetryPolicy{
+			ShouldRetry: RetryOnServerErrors,
+		},
+	})
+	a.NilNow(err)
+	a.EqualNow(resp.Header.Get("X-Attempt"), "2")
+	a.TrueNow(time.Since(start) >= time.Second)
+
+	// the context is timeout before the next attempt
+	url := retryURL + "?key=retry-after-timeout&failures=1&retryAfter=10"
+	_, err = Request(url, RequestOptions{
+		MaxAttempt: 2,
+		Timeout:    100,
+		RetryPolicy: &RetryPolicy{
+			ShouldRetry: RetryOnServerErrors,
+		},
+	})
+	a.NotNilNow(err)
+	a.TrueNow(err == context.DeadlineExceeded)
+}
+
+func TestRetryPolicyGetDelay(t *testing.T) {
+	a := assert.New(t)
+
+	policy := &RetryPolicy{}
+	a.EqualNow(policy.getDelay(1, nil), time.Duration(0))
+
+	policy = &RetryPolicy{BaseDelay: 100 * time.Millisecond}
+	a.EqualNow(policy.getDelay(1, nil), 100*time.Millisecond)
+	a.EqualNow(policy.getDelay(2, nil), 200*time.Millisecond)
+	a.EqualNow(policy.getDelay(3, nil), 400*time.Millisecond)
+
+	policy = &RetryPolicy{BaseDelay: 100 * time.Millisecond, Multiplier: 3, MaxDelay: time.Second}
+	a.EqualNow(policy.getDelay(2, nil), 300*time.Millisecond)
+	a.EqualNow(policy.getDelay(3, nil), 900*time.Millisecond)
+	a.EqualNow(policy.getDelay(4, nil), time.Second)
+	a.EqualNow(policy.getDelay(1000, nil), time.Second)
+
+	policy = &RetryPolicy{BaseDelay: 100 * time.Millisecond, Jitter: 0.2}
+	for i := 0; i < 100; i++ {
+		delay := policy.getDelay(1, nil)
+		a.TrueNow(delay >= 80*time.Millisecond && delay <= 120*time.Millisecond)
+	}
+
+	resp := &http.Response{Header: http.Header{"Retry-After": {"2"}}}
+	policy = &RetryPolicy{BaseDelay: 100 * time.Millisecond}
+	a.EqualNow(policy.getDelay(1, resp), 2*time.Second)
+	policy = &RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	a.EqualNow(policy.getDelay(1, resp), time.Second)
+	policy = &RetryPolicy{BaseDelay: 100 * time.Millisecond, IgnoreRetryAfter: true}
+	a.EqualNow(policy.getDelay(1, resp), 100*time.Millisecond)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	a := assert.New(t)
+
+	_, ok := parseRetryAfter("")
+	a.NotTrueNow(ok)
+
+	_, ok = parseRetryAfter("-1")
+	a.NotTrueNow(ok)
+
+	_, ok = parseRetryAfter("invalid")
+	a.NotTrueNow(ok)
+
+	delay, ok := parseRetryAfter("120")
+	a.TrueNow(ok)
+	a.EqualNow(delay, 120*time.Second)
+
+	delay, ok = parseRetryAfter(time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat))
+	a.TrueNow(ok)
+	a.EqualNow(delay, time.Duration(0))
+
+	delay, ok = parseRetryAfter(time.Now().Add(time.Minute).UTC().Format(http.TimeFormat))
+	a.TrueNow(ok)
+	a.TrueNow(delay > 55*time.Second && delay <= time.Minute)
+}
+
+func TestRetryWithBody(t *testing.T) {
+	a := assert.New(t)
+
+	url := retryURL + "?key=retry-body&failures=2"
+	data, resp, err := ToObject[testResponse](POST(url, RequestOptions{
+		Body:       map[string]string{"greeting": "Hello"},
+		MaxAttempt: 3,
+		RetryPolicy: &RetryPolicy{
+			ShouldRetry: RetryOnServerErrors,
+		},
+	}))
+	a.NilNow(err)
+	a.EqualNow(resp.Header.Get("X-Attempt"), "3")
+	a.EqualNow(*data.Body, `{"greeting":"Hello"}`)
+
+	_, err = Req(retryURL+"?key=retry-multipart&failures=1").
+		POST().
+		AddFormField("greeting", "Hello").
+		SetAttempt(3).
+		SetRetryPolicy(RetryPolicy{ShouldRetry: RetryOnServerErrors}).
+		Do()
+	a.NotNilNow(err)
+	a.TrueNow(errors.Is(err, ErrBodyNotReplayable))
+}
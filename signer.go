@@ -0,0 +1,404 @@
+package request
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Signer signs the requests after the request interceptors, and the headers and the body of the
+// request are final at that time. The signer will be called again before re-sending the request
+// for retrying or responding to the challenge.
+type Signer interface {
+	// Sign signs the request, for example, by setting the signature to the request headers.
+	Sign(req *SigningRequest) error
+}
+
+// SignerFunc is an adapter to allow the use of ordinary functions as signers.
+//
+//	cli := request.New(request.Config{
+//	  Signer: request.SignerFunc(func(req *request.SigningRequest) error {
+//	    req.Request.Header.Set("X-Body-Hash", req.BodyHash)
+//	    return nil
+//	  }),
+//	})
+type SignerFunc func(req *SigningRequest) error
+
+// Sign calls the function with the request.
+func (fn SignerFunc) Sign(req *SigningRequest) error {
+	return fn(req)
+}
+
+// UnsignedPayload is the value of the body hash if the body of the request is a stream that can't
+// be read again, like a multipart form.
+const UnsignedPayload string = "UNSIGNED-PAYLOAD"
+
+// SigningRequest is the request to be signed, it provides the body of the request and the helpers
+// to build the canonical request.
+type SigningRequest struct {
+	// Request is the request to be signed.
+	Request *http.Request
+	// Body is the content of the request body, and it's nil if the request has no body or the body
+	// can't be read again.
+	Body []byte
+	// BodyHash is the hex-encoded SHA-256 hash of the request body, and it's "UNSIGNED-PAYLOAD" if
+	// the body can't be read again.
+	BodyHash string
+}
+
+// newSigningRequest reads the body of the request by the `GetBody` function of the request, and
+// creates a signing request.
+func newSigningRequest(req *http.Request) (*SigningRequest, error) {
+	signingReq := &SigningRequest{Request: req}
+
+	if req.Body == nil || req.Body == http.NoBody {
+		signingReq.Body = []byte{}
+	} else if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		defer body.Close()
+
+		data, err := io.ReadAll(body)
+		if err != nil {
+			return nil, err
+		}
+		signingReq.Body = data
+	}
+
+	if signingReq.Body == nil {
+		signingReq.BodyHash = UnsignedPayload
+	} else {
+		hash := sha256.Sum256(signingReq.Body)
+		signingReq.BodyHash = hex.EncodeToString(hash[:])
+	}
+
+	return signingReq, nil
+}
+
+// CanonicalURI returns the URI-encoded path of the request, and each segment of the path is
+// encoded except the unreserved characters.
+func (sr *SigningRequest) CanonicalURI() string {
+	path := sr.Request.URL.Path
+	if path == "" {
+		return "/"
+	}
+
+	return uriEncode(path, false)
+}
+
+// CanonicalQueryString returns the query string that the parameters are sorted by the names and
+// the values, and the names and the values are URI-encoded.
+func (sr *SigningRequest) CanonicalQueryString() string {
+	query, _ := url.ParseQuery(sr.Request.URL.RawQuery)
+	params := make([]string, 0, len(query))
+
+	for key, values := range query {
+		encodedKey := uriEncode(key, true)
+		for _, value := range values {
+			params = append(params, encodedKey+"="+uriEncode(value, true))
+		}
+	}
+	sort.Strings(params)
+
+	return strings.Join(params, "&")
+}
+
+// CanonicalHeaders returns the canonical headers and the signed headers for the header fields.
+// The names of the fields are converted to lowercase and sorted, and the values are trimmed. The
+// `Host` field will be included if it's in the names.
+func (sr *SigningRequest) CanonicalHeaders(names []string) (canonical string, signed string) {
+	headers := make(map[string]string, len(names))
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "host" {
+			headers[name] = sr.host()
+			continue
+		}
+
+		values := sr.Request.Header.Values(name)
+		trimmed := make([]string, 0, len(values))
+		for _, value := range values {
+			trimmed = append(trimmed, strings.Join(strings.Fields(value), " "))
+		}
+		headers[name] = strings.Join(trimmed, ",")
+	}
+
+	keys := make([]string, 0, len(headers))
+	for key := range headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	builder := strings.Builder{}
+	for _, key := range keys {
+		builder.WriteString(key + ":" + headers[key] + "\n")
+	}
+
+	return builder.String(), strings.Join(keys, ";")
+}
+
+// CanonicalRequest returns the canonical request with the header fields to be signed, it's the
+// HTTP method, the canonical URI, the canonical query string, the canonical headers, the signed
+// headers, and the body hash, separated by the newline characters.
+func (sr *SigningRequest) CanonicalRequest(headers []string) string {
+	return sr.canonicalRequest(sr.CanonicalURI(), headers)
+}
+
+// canonicalRequest returns the canonical request with the canonical URI.
+func (sr *SigningRequest) canonicalRequest(uri string, headers []string) string {
+	canonicalHeaders, signedHeaders := sr.CanonicalHeaders(headers)
+
+	return strings.Join([]string{
+		sr.Request.Method,
+		uri,
+		sr.CanonicalQueryString(),
+		canonicalHeaders,
+		signedHeaders,
+		sr.BodyHash,
+	}, "\n")
+}
+
+// host returns the host of the request.
+func (sr *SigningRequest) host() string {
+	if sr.Request.Host != "" {
+		return sr.Request.Host
+	}
+
+	return sr.Request.URL.Host
+}
+
+// uriEncode encodes the string except the unreserved characters (RFC 3986), and the slashes will
+// not be encoded if it's not a query component.
+func uriEncode(s string, isQuery bool) string {
+	const hex = "0123456789ABCDEF"
+	builder := strings.Builder{}
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' || (c == '/' && !isQuery) {
+			builder.WriteByte(c)
+		} else {
+			builder.WriteByte('%')
+			builder.WriteByte(hex[c>>4])
+			builder.WriteByte(hex[c&15])
+		}
+	}
+
+	return builder.String()
+}
+
+// SigV4Signer signs the requests by the AWS Signature Version 4. It signs the `Host` field and all
+// the header fields of the request except the `Authorization` and the `User-Agent` fields.
+//
+//	cli := request.New(request.Config{
+//	  Signer: &request.SigV4Signer{
+//	    AccessKeyID:     "AKIDEXAMPLE",
+//	    SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+//	    Region:          "us-east-1",
+//	    Service:         "execute-api",
+//	  },
+//	})
+type SigV4Signer struct {
+	// AccessKeyID is the access key ID of the credentials.
+	AccessKeyID string
+	// SecretAccessKey is the secret access key of the credentials.
+	SecretAccessKey string
+	// SessionToken is the session token of the temporary credentials, and it'll be sent as the
+	// `X-Amz-Security-Token` field.
+	SessionToken string
+	// Region is the region of the service, like "us-east-1".
+	Region string
+	// Service is the name of the service, like "s3" or "execute-api".
+	Service string
+
+	// now returns the signing time, it's for testing only.
+	now func() time.Time
+}
+
+// sigV4Algorithm is the algorithm of the AWS Signature Version 4.
+const sigV4Algorithm = "AWS4-HMAC-SHA256"
+
+// sigV4IgnoredHeaders are the header fields that will not be signed. The `Cookie` field is ignored
+// because the cookies of the cookie jar are added to it after the request is signed.
+var sigV4IgnoredHeaders = map[string]bool{
+	"authorization":   true,
+	"cookie":          true,
+	"user-agent":      true,
+	"x-amzn-trace-id": true,
+}
+
+// Sign signs the request, and sets the signature to the `Authorization` field of the request
+// headers. It also sets the `X-Amz-Date` field, and the `X-Amz-Content-Sha256` field for the S3
+// service.
+func (signer *SigV4Signer) Sign(req *SigningRequest) error {
+	now := time.Now
+	if signer.now != nil {
+		now = signer.now
+	}
+	t := now().UTC()
+	amzDate := t.Format("20060102T150405Z")
+	date := t.Format("20060102")
+
+	header := req.Request.Header
+	header.Del("Authorization")
+	header.Set("X-Amz-Date", amzDate)
+	if signer.SessionToken != "" {
+		header.Set("X-Amz-Security-Token", signer.SessionToken)
+	}
+	if signer.Service == "s3" {
+		header.Set("X-Amz-Content-Sha256", req.BodyHash)
+	}
+
+	headers := []string{"host"}
+	for name := range header {
+		if !sigV4IgnoredHeaders[strings.ToLower(name)] {
+			headers = append(headers, name)
+		}
+	}
+
+	uri := req.CanonicalURI()
+	if signer.Service != "s3" {
+		uri = uriEncode(uri, false)
+	}
+	canonicalRequest := req.canonicalRequest(uri, headers)
+	_, signedHeaders := req.CanonicalHeaders(headers)
+
+	scope := strings.Join([]string{date, signer.Region, signer.Service, "aws4_request"}, "/")
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		sigV4Algorithm, amzDate, scope, hex.EncodeToString(hash[:]),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+signer.SecretAccessKey), date)
+	key = hmacSHA256(key, signer.Region)
+	key = hmacSHA256(key, signer.Service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	header.Set("Authorization", sigV4Algorithm+" Credential="+signer.AccessKeyID+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+
+	return nil
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of the data with the key.
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// HMACSigner signs the requests by HMAC. It sets the current unix time to the timestamp field,
+// and the signature is the HMAC of the timestamp and the canonical request with the signed header
+// fields, separated by a newline character.
+//
+//	cli := request.New(request.Config{
+//	  Signer: &request.HMACSigner{
+//	    Key:           []byte("secret"),
+//	    SignedHeaders: []string{"Host", "Content-Type"},
+//	  },
+//	})
+type HMACSigner struct {
+	// Key is the secret key of the HMAC.
+	Key []byte
+	// Hash returns the hash function of the HMAC, default SHA-256.
+	Hash func() hash.Hash
+	// Header is the name of the header field to set the signature, default "X-Signature".
+	Header string
+	// TimestampHeader is the name of the header field to set the signing time, default
+	// "X-Timestamp". The timestamp field will always be signed.
+	TimestampHeader string
+	// SignedHeaders are the names of the header fields to be signed.
+	SignedHeaders []string
+	// StringToSign builds the string to be signed from the request, and it overwrites the default
+	// string to sign.
+	StringToSign func(req *SigningRequest) (string, error)
+	// Encode encodes the signature to a string, default hex encoding.
+	Encode func(signature []byte) string
+
+	// now returns the signing time, it's for testing only.
+	now func() time.Time
+}
+
+// Sign signs the request, and sets the signature to the signature field of the request headers.
+func (signer *HMACSigner) Sign(req *SigningRequest) error {
+	now := time.Now
+	if signer.now != nil {
+		now = signer.now
+	}
+
+	timestampHeader := signer.TimestampHeader
+	if timestampHeader == "" {
+		timestampHeader = "X-Timestamp"
+	}
+	timestamp := strconv.FormatInt(now().Unix(), 10)
+	req.Request.Header.Set(timestampHeader, timestamp)
+
+	var stringToSign string
+	if signer.StringToSign != nil {
+		s, err := signer.StringToSign(req)
+		if err != nil {
+			return err
+		}
+		stringToSign = s
+	} else {
+		headers := append([]string{timestampHeader}, signer.SignedHeaders...)
+		stringToSign = timestamp + "\n" + req.CanonicalRequest(headers)
+	}
+
+	newHash := signer.Hash
+	if newHash == nil {
+		newHash = sha256.New
+	}
+	mac := hmac.New(newHash, signer.Key)
+	mac.Write([]byte(stringToSign))
+
+	encode := signer.Encode
+	if encode == nil {
+		encode = hex.EncodeToString
+	}
+
+	header := signer.Header
+	if header == "" {
+		header = "X-Signature"
+	}
+	req.Request.Header.Set(header, encode(mac.Sum(nil)))
+
+	return nil
+}
+
+// getSigner returns the signer from the request options or the client config.
+func (cli *Client) getSigner(opt RequestOptions) Signer {
+	if opt.Signer != nil {
+		return opt.Signer
+	}
+
+	return cli.Signer
+}
+
+// signRequest signs the request by the signer of the request, it does nothing if no signer is
+// set.
+func (cli *Client) signRequest(req *http.Request, opt RequestOptions) error {
+	signer := cli.getSigner(opt)
+	if signer == nil {
+		return nil
+	}
+
+	signingReq, err := newSigningRequest(req)
+	if err != nil {
+		return err
+	}
+
+	return signer.Sign(signingReq)
+}
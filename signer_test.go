@@ -0,0 +1,219 @@
+package request
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ghosind/go-assert"
+)
+
+func TestSigV4Signer(t *testing.T) {
+	a := assert.New(t)
+
+	signer := &SigV4Signer{
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+		Region:          "us-east-1",
+		Service:         "service",
+		now: func() time.Time {
+			return time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+		},
+	}
+	cli := New(Config{Signer: signer})
+	credential := "AWS4-HMAC-SHA256 " +
+		"Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, "
+
+	// test cases from the AWS Signature Version 4 test suite
+	req, _ := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	a.NilNow(cli.signRequest(req, RequestOptions{}))
+	a.EqualNow(req.Header.Get("X-Amz-Date"), "20150830T123600Z")
+	a.EqualNow(req.Header.Get("Authorization"), credential+"SignedHeaders=host;x-amz-date, "+
+		"Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31")
+
+	// the cookies are not signed, the cookie jar may add cookies after signing
+	req, _ = http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	req.Header.Set("Cookie", "session=1")
+	a.NilNow(cli.signRequest(req, RequestOptions{}))
+	a.EqualNow(req.Header.Get("Authorization"), credential+"SignedHeaders=host;x-amz-date, "+
+		"Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31")
+
+	req, _ = http.NewRequest(http.MethodGet,
+		"https://example.amazonaws.com/?Param2=value2&Param1=value1", nil)
+	a.NilNow(cli.signRequest(req, RequestOptions{}))
+	a.EqualNow(req.Header.Get("Authorization"), credential+"SignedHeaders=host;x-amz-date, "+
+		"Signature=b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500")
+
+	req, _ = http.NewRequest(http.MethodPost, "https://example.amazonaws.com/",
+		strings.NewReader("Param1=value1"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	a.NilNow(cli.signRequest(req, RequestOptions{}))
+	a.EqualNow(req.Header.Get("Authorization"), credential+
+		"SignedHeaders=content-type;host;x-amz-date, "+
+		"Signature=ff11897932ad3f4e8b18135d722051e5ac45fc38421b1da7b9d196a0fe09473a")
+
+	// re-sign the request
+	a.NilNow(cli.signRequest(req, RequestOptions{}))
+	a.EqualNow(req.Header.Values("Authorization"), []string{credential +
+		"SignedHeaders=content-type;host;x-amz-date, " +
+		"Signature=ff11897932ad3f4e8b18135d722051e5ac45fc38421b1da7b9d196a0fe09473a"})
+
+	s3Signer := *signer
+	s3Signer.Service = "s3"
+	s3Signer.SessionToken = "session-token"
+	req, _ = http.NewRequest(http.MethodGet, "https://example.amazonaws.com/a%20b", nil)
+	a.NilNow(cli.signRequest(req, RequestOptions{Signer: &s3Signer}))
+	a.EqualNow(req.Header.Get("X-Amz-Content-Sha256"),
+		"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855")
+	a.EqualNow(req.Header.Get("X-Amz-Security-Token"), "session-token")
+	a.TrueNow(strings.Contains(req.Header.Get("Authorization"),
+		"SignedHeaders=host;x-amz-content-sha256;x-amz-date;x-amz-security-token, "))
+}
+
+func TestHMACSigner(t *testing.T) {
+	a := assert.New(t)
+
+	signer := &HMACSigner{
+		Key:           []byte("secret"),
+		SignedHeaders: []string{"Host", "Content-Type"},
+		now: func() time.Time {
+			return time.Unix(1700000000, 0)
+		},
+	}
+
+	data, _, err := ToObject[testResponse](POST("http://127.0.0.1:8080/test?b=2&a=1",
+		RequestOptions{
+			Body:   map[string]any{"data": "Hello world"},
+			Signer: signer,
+		}))
+	a.NilNow(err)
+
+	bodyHash := sha256.Sum256([]byte(`{"data":"Hello world"}`))
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte(strings.Join([]string{
+		"1700000000",
+		"POST",
+		"/test",
+		"a=1&b=2",
+		"content-type:application/json\nhost:127.0.0.1:8080\nx-timestamp:1700000000\n",
+		"content-type;host;x-timestamp",
+		hex.EncodeToString(bodyHash[:]),
+	}, "\n")))
+
+	headers := *data.Headers
+	a.EqualNow(headers["X-Timestamp"], []string{"1700000000"})
+	a.EqualNow(headers["X-Signature"], []string{hex.EncodeToString(mac.Sum(nil))})
+
+	// custom string to sign
+	data, _, err = ToObject[testResponse](Req("http://127.0.0.1:8080/test").
+		SetSigner(&HMACSigner{
+			Key:             []byte("secret"),
+			Header:          "X-Sig",
+			TimestampHeader: "X-Time",
+			StringToSign: func(req *SigningRequest) (string, error) {
+				return req.Request.Method + " " + req.Request.URL.Path, nil
+			},
+			Encode: func(signature []byte) string {
+				return "custom-" + hex.EncodeToString(signature)
+			},
+		}).
+		Do())
+	a.NilNow(err)
+
+	mac = hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte("GET /test"))
+	a.EqualNow((*data.Headers)["X-Sig"], []string{"custom-" + hex.EncodeToString(mac.Sum(nil))})
+	a.NotNilNow((*data.Headers)["X-Time"])
+
+	_, err = GET("http://127.0.0.1:8080/test", RequestOptions{
+		Signer: &HMACSigner{
+			StringToSign: func(req *SigningRequest) (string, error) {
+				return "", errors.New("expected error")
+			},
+		},
+	})
+	a.NotNilNow(err)
+}
+
+func TestSignerAfterInterceptors(t *testing.T) {
+	a := assert.New(t)
+
+	cli := New()
+	cli.UseRequestInterceptor(func(req *http.Request) error {
+		req.Header.Set("X-Custom", "custom")
+		return nil
+	})
+
+	signed := make([]*SigningRequest, 0)
+	signer := SignerFunc(func(req *SigningRequest) error {
+		signed = append(signed, req)
+		req.Request.Header.Set("X-Body-Hash", req.BodyHash)
+		return nil
+	})
+
+	data, _, err := ToObject[testResponse](cli.POST("http://127.0.0.1:8080/test", RequestOptions{
+		Body:   "Hello world",
+		Signer: signer,
+	}))
+	a.NilNow(err)
+	a.EqualNow(len(signed), 1)
+	a.EqualNow(signed[0].Request.Header.Get("X-Custom"), "custom")
+	a.EqualNow(string(signed[0].Body), "Hello world")
+	a.EqualNow((*data.Headers)["X-Body-Hash"], []string{
+		"64ec88ca00b268e5ba1a35678a1b5316d212f4f366b2477232534a8aeca37f3c",
+	})
+
+	// the stream body can't be signed
+	signed = signed[:0]
+	_, err = cli.Req("http://127.0.0.1:8080/test").
+		POST().
+		AddFormField("name", "value").
+		SetSigner(signer).
+		Do()
+	a.NilNow(err)
+	a.EqualNow(len(signed), 1)
+	a.NilNow(signed[0].Body)
+	a.EqualNow(signed[0].BodyHash, UnsignedPayload)
+
+	// sign the request for every attempt
+	signed = signed[:0]
+	_, err = cli.POST(retryURL+"?key=signer-retry&failures=1", RequestOptions{
+		Body:        "Hello world",
+		MaxAttempt:  2,
+		RetryPolicy: &RetryPolicy{ShouldRetry: RetryOnServerErrors},
+		Signer:      signer,
+	})
+	a.NilNow(err)
+	a.EqualNow(len(signed), 2)
+}
+
+func TestSigningRequestCanonical(t *testing.T) {
+	a := assert.New(t)
+
+	req, _ := http.NewRequest(http.MethodGet, "http://example.com/a b/c~d?b=2&a=2&a=1&c=x+y", nil)
+	req.Header.Add("X-Multi", " a   b ")
+	req.Header.Add("X-Multi", "c")
+	sr, err := newSigningRequest(req)
+	a.NilNow(err)
+
+	a.EqualNow(sr.CanonicalURI(), "/a%20b/c~d")
+	a.EqualNow(sr.CanonicalQueryString(), "a=1&a=2&b=2&c=x%20y")
+
+	canonical, signed := sr.CanonicalHeaders([]string{"X-Multi", "Host"})
+	a.EqualNow(canonical, "host:example.com\nx-multi:a b,c\n")
+	a.EqualNow(signed, "host;x-multi")
+	a.EqualNow(req.Header.Values("X-Multi"), []string{" a   b ", "c"})
+
+	a.EqualNow(sr.CanonicalRequest([]string{"Host"}), "GET\n/a%20b/c~d\na=1&a=2&b=2&c=x%20y\n"+
+		"host:example.com\n\nhost\n"+
+		"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855")
+
+	req.URL.Path = ""
+	a.EqualNow(sr.CanonicalURI(), "/")
+	a.EqualNow(uriEncode("a/b c", true), "a%2Fb%20c")
+}
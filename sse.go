@@ -0,0 +1,349 @@
+package request
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SSEEvent is an event of the Server-Sent Events stream.
+type SSEEvent struct {
+	// ID is the last event ID of the stream when the event is dispatched.
+	ID string
+	// Event is the type of the event, default "message".
+	Event string
+	// Data is the data of the event, and the lines of the data are joined by "\n".
+	Data string
+	// Retry is the reconnection time that is set by the event, and it's 0 if the event doesn't have
+	// the retry field.
+	Retry time.Duration
+}
+
+// SSEStream is the stream of the Server-Sent Events. It reconnects automatically with the
+// `Last-Event-ID` header when the connection is closed or fails by network errors, after the
+// reconnection time that is set by the server (default 3 seconds). It stops if the server
+// responds with an invalid status code, a content type other than `text/event-stream`, or the 204
+// (No Content) status code, and it also stops if the request fails by other errors like an invalid
+// URL or a TLS error.
+type SSEStream struct {
+	events chan *SSEEvent
+	done   chan struct{}
+	cancel context.CancelFunc
+	closed bool
+	err    error
+	// lastEventID is the last event ID of the stream.
+	lastEventID string
+	// retry is the reconnection time of the stream.
+	retry time.Duration
+	// mutex is the locker for the state of the stream.
+	mutex sync.Mutex
+}
+
+const (
+	// defaultSSERetry is the default reconnection time of the SSE streams.
+	defaultSSERetry = 3 * time.Second
+	// sseMaxLineSize is the maximum size of a line in the SSE streams.
+	sseMaxLineSize = 1 << 20
+)
+
+// SSE connects to the Server-Sent Events stream of the URL with the request options, and returns
+// the stream to receive the events. The `Timeout` field of the options is ignored for the
+// long-lived stream, and the stream can be stopped by the `Context` of the options or the `Close`
+// method of the stream.
+//
+//	stream := cli.SSE("https://example.com/events")
+//	defer stream.Close()
+//
+//	for event := range stream.Events() {
+//	  // Event handling
+//	}
+//	if err := stream.Err(); err != nil {
+//	  // Error handling
+//	}
+func (cli *Client) SSE(url string, opts ...RequestOptions) *SSEStream {
+	var opt RequestOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+
+	parent := opt.Context
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithCancel(parent)
+
+	stream := &SSEStream{
+		events: make(chan *SSEEvent),
+		done:   make(chan struct{}),
+		cancel: cancel,
+		retry:  defaultSSERetry,
+	}
+	for k, v := range opt.Headers {
+		if strings.EqualFold(k, "Last-Event-ID") && len(v) > 0 {
+			stream.lastEventID = v[0]
+		}
+	}
+
+	go stream.run(ctx, cli, url, opt)
+
+	return stream
+}
+
+// Events returns the channel of the events, and the channel will be closed when the stream stops.
+func (stream *SSEStream) Events() <-chan *SSEEvent {
+	return stream.events
+}
+
+// Err returns the error that stops the stream. It returns nil if the stream is closed by the
+// `Close` method or the server, and it should be called after the events channel is closed.
+func (stream *SSEStream) Err() error {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+
+	return stream.err
+}
+
+// LastEventID returns the last event ID of the stream.
+func (stream *SSEStream) LastEventID() string {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+
+	return stream.lastEventID
+}
+
+// Close stops the stream and waits for the connection to be closed.
+func (stream *SSEStream) Close() error {
+	stream.mutex.Lock()
+	stream.closed = true
+	stream.mutex.Unlock()
+
+	stream.cancel()
+	<-stream.done
+
+	return nil
+}
+
+// run connects to the server and reconnects until the stream stops.
+func (stream *SSEStream) run(ctx context.Context, cli *Client, url string, opt RequestOptions) {
+	defer close(stream.done)
+	defer close(stream.events)
+	defer stream.cancel()
+
+	for {
+		retry, err := stream.connect(ctx, cli, url, opt)
+		if err == nil && !retry {
+			return
+		}
+		if ctx.Err() != nil {
+			err = ctx.Err()
+			retry = false
+		}
+		if !retry {
+			stream.mutex.Lock()
+			if !stream.closed {
+				stream.err = err
+			}
+			stream.mutex.Unlock()
+			return
+		}
+
+		timer := time.NewTimer(stream.getRetry())
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// connect sends the request and reads the events until the connection is closed. It returns
+// whether to reconnect, and the error that stops the stream.
+func (stream *SSEStream) connect(
+	ctx context.Context,
+	cli *Client,
+	url string,
+	opt RequestOptions,
+) (bool, error) {
+	headers := make(http.Header, len(opt.Headers)+3)
+	for k, v := range opt.Headers {
+		headers[http.CanonicalHeaderKey(k)] = v
+	}
+	headers.Set("Accept", "text/event-stream")
+	headers.Set("Cache-Control", "no-cache")
+	if lastEventID := stream.LastEventID(); lastEventID != "" {
+		headers.Set("Last-Event-ID", lastEventID)
+	} else {
+		headers.Del("Last-Event-ID")
+	}
+
+	opt.Headers = headers
+	opt.Context = ctx
+	opt.Timeout = 0
+	// the stream never ends, so it can't be stored in the cache.
+	opt.DisableCache = true
+
+	resp, err := cli.request("", url, opt)
+	if err != nil {
+		closeResponseBody(resp)
+		return isSSEReconnectable(err), err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNoContent {
+		return false, nil
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/event-stream" {
+		return false, fmt.Errorf("%w: unexpected content type %q for the event stream",
+			ErrInvalidResp, resp.Header.Get("Content-Type"))
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 4096), sseMaxLineSize)
+	scanner.Split(scanSSELines)
+
+	if err := stream.readEvents(ctx, scanner); err != nil {
+		return isSSEReconnectable(err), err
+	}
+
+	return true, nil
+}
+
+// isSSEReconnectable checks whether the stream can reconnect after the error. Only the network
+// errors, the unexpected end of the connection, and the temporary rejections of the circuit
+// breaker or the bulkhead are reconnectable, and other errors like an invalid URL, an unknown
+// host, or a TLS error stop the stream.
+func isSSEReconnectable(err error) bool {
+	var dnsErr *net.DNSError
+	var opErr *net.OpError
+	var netErr net.Error
+
+	switch {
+	case errors.As(err, &dnsErr) && dnsErr.IsNotFound:
+		return false
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return true
+	case errors.As(err, &opErr):
+		return true
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return true
+	case errors.Is(err, ErrCircuitOpen), errors.Is(err, ErrBulkheadFull):
+		return true
+	default:
+		return false
+	}
+}
+
+// readEvents parses the lines of the stream, and sends the events to the channel.
+func (stream *SSEStream) readEvents(ctx context.Context, scanner *bufio.Scanner) error {
+	event := new(SSEEvent)
+	data := new(strings.Builder)
+	first := true
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if first {
+			line = strings.TrimPrefix(line, "\uFEFF")
+			first = false
+		}
+
+		if line == "" {
+			if data.Len() == 0 {
+				event = new(SSEEvent)
+				continue
+			}
+
+			event.ID = stream.LastEventID()
+			event.Data = strings.TrimSuffix(data.String(), "\n")
+			if event.Event == "" {
+				event.Event = "message"
+			}
+
+			select {
+			case stream.events <- event:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+
+			event = new(SSEEvent)
+			data.Reset()
+			continue
+		} else if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+
+		switch field {
+		case "event":
+			event.Event = value
+		case "data":
+			data.WriteString(value)
+			data.WriteByte('\n')
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				stream.mutex.Lock()
+				stream.lastEventID = value
+				stream.mutex.Unlock()
+			}
+		case "retry":
+			if ms, err := strconv.ParseUint(value, 10, 63); err == nil {
+				event.Retry = time.Duration(ms) * time.Millisecond
+				stream.mutex.Lock()
+				stream.retry = event.Retry
+				stream.mutex.Unlock()
+			}
+		}
+	}
+
+	return scanner.Err()
+}
+
+// getRetry returns the reconnection time of the stream.
+func (stream *SSEStream) getRetry() time.Duration {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+
+	return stream.retry
+}
+
+// scanSSELines is the split function for the lines of the SSE streams, and the lines can be ended
+// by "\r\n", "\n", or "\r".
+func scanSSELines(data []byte, atEOF bool) (int, []byte, error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		if data[i] == '\n' {
+			return i + 1, data[:i], nil
+		} else if i+1 < len(data) {
+			if data[i+1] == '\n' {
+				return i + 2, data[:i], nil
+			}
+			return i + 1, data[:i], nil
+		} else if atEOF {
+			return i + 1, data[:i], nil
+		}
+		// wait for the next byte to check whether it's "\r\n".
+		return 0, nil, nil
+	}
+
+	if atEOF {
+		// the incomplete line at the end of the stream is discarded.
+		return len(data), nil, nil
+	}
+
+	return 0, nil, nil
+}
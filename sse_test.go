@@ -0,0 +1,166 @@
+package request
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ghosind/go-assert"
+)
+
+func TestSSE(t *testing.T) {
+	a := assert.New(t)
+
+	stream := SSE("http://127.0.0.1:8080/sse?key=sse-reconnect&connections=2&retry=10")
+	defer stream.Close()
+
+	events := make([]*SSEEvent, 0)
+	for event := range stream.Events() {
+		events = append(events, event)
+	}
+	a.NilNow(stream.Err())
+	a.EqualNow(len(events), 6)
+	a.EqualNow(stream.LastEventID(), "5")
+
+	for i, event := range events {
+		a.EqualNow(event.ID, fmt.Sprint(i))
+		a.EqualNow(event.Event, "tick")
+		a.EqualNow(event.Data, fmt.Sprintf("event %d\nattempt %d", i, i/3+1))
+	}
+}
+
+func TestSSEWithOptions(t *testing.T) {
+	a := assert.New(t)
+
+	// the client timeout doesn't apply to the stream
+	cli := New(Config{Timeout: 50})
+	stream := cli.SSE("http://127.0.0.1:8080/sse?key=sse-options&hold=1&crlf=1", RequestOptions{
+		Headers: map[string][]string{
+			"Last-Event-ID": {"9"},
+		},
+	})
+	time.Sleep(100 * time.Millisecond)
+
+	for i := 10; i < 13; i++ {
+		event := <-stream.Events()
+		a.EqualNow(event.ID, fmt.Sprint(i))
+		a.EqualNow(event.Data, fmt.Sprintf("event %d\nattempt 1", i))
+	}
+
+	a.NilNow(stream.Close())
+	_, ok := <-stream.Events()
+	a.NotTrueNow(ok)
+	a.NilNow(stream.Err())
+}
+
+func TestSSEWithContext(t *testing.T) {
+	a := assert.New(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	stream := SSE("http://127.0.0.1:8080/sse?key=sse-context&hold=1", RequestOptions{
+		Context: ctx,
+	})
+	count := 0
+	for range stream.Events() {
+		count++
+	}
+	a.EqualNow(count, 3)
+	a.TrueNow(errors.Is(stream.Err(), context.DeadlineExceeded))
+}
+
+func TestSSEWithInvalidResponse(t *testing.T) {
+	a := assert.New(t)
+
+	stream := SSE("http://127.0.0.1:8080/test")
+	for range stream.Events() {
+		t.Error("unexpected event")
+	}
+	a.TrueNow(errors.Is(stream.Err(), ErrInvalidResp))
+
+	stream = SSE("http://127.0.0.1:8080/status?status=500")
+	for range stream.Events() {
+		t.Error("unexpected event")
+	}
+	var respErr *ResponseError
+	a.TrueNow(errors.As(stream.Err(), &respErr))
+	a.EqualNow(respErr.StatusCode, http.StatusInternalServerError)
+}
+
+func TestSSEWithPermanentError(t *testing.T) {
+	a := assert.New(t)
+
+	for _, url := range []string{"http://127.0.0.1:8080/%zz", "https://127.0.0.1:8080/sse"} {
+		stream := SSE(url)
+		select {
+		case _, ok := <-stream.Events():
+			a.NotTrueNow(ok)
+		case <-time.After(time.Second):
+			t.Fatalf("the stream of %s is not stopped", url)
+		}
+		a.NotNilNow(stream.Err())
+	}
+
+	// reconnect after the network error
+	stream := SSE("http://127.0.0.1:1/sse", RequestOptions{
+		Headers: map[string][]string{"Last-Event-ID": {"1"}},
+	})
+	select {
+	case <-stream.Events():
+		t.Fatal("the stream is stopped")
+	case <-time.After(100 * time.Millisecond):
+	}
+	a.NilNow(stream.Close())
+	a.NilNow(stream.Err())
+}
+
+func TestSSEParseEvents(t *testing.T) {
+	a := assert.New(t)
+
+	body := "\uFEFFdata:first\r" +
+		": comment\n" +
+		"data\n" +
+		"data:  third\n" +
+		"\n" +
+		"event: custom\n" +
+		"id: 1\n" +
+		"retry: 100\n" +
+		"data: second\r\n" +
+		"\r\n" +
+		"id: 2\x00\n" +
+		"retry: invalid\n" +
+		"unknown: field\n" +
+		"data\n" +
+		"\n" +
+		"event: ignored\n" +
+		"\n" +
+		"id: 3\n" +
+		"data: incomplete"
+
+	stream := &SSEStream{
+		events: make(chan *SSEEvent, 10),
+		retry:  defaultSSERetry,
+	}
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	scanner.Split(scanSSELines)
+	a.NilNow(stream.readEvents(context.Background(), scanner))
+	close(stream.events)
+
+	events := make([]*SSEEvent, 0)
+	for event := range stream.events {
+		events = append(events, event)
+	}
+	a.EqualNow(events, []*SSEEvent{
+		{Event: "message", Data: "first\n\n third"},
+		{ID: "1", Event: "custom", Data: "second", Retry: 100 * time.Millisecond},
+		{ID: "1", Event: "message", Data: ""},
+	})
+	a.EqualNow(stream.LastEventID(), "3")
+	a.EqualNow(stream.getRetry(), 100*time.Millisecond)
+}
@@ -0,0 +1,160 @@
+package request
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// ToStream decodes the response body as a JSON array element by element, and calls the function
+// with every element without reading the whole body into memory. It stops and returns the error
+// if the function returns an error. An empty body is handled as an empty array. The method will
+// close the body of the response that after read.
+//
+//	resp, err := request.GET("https://example.com/products")
+//	resp, err = request.ToStream(resp, err, func(product *Product) error {
+//	  // Element handling
+//	  return nil
+//	})
+func ToStream[T any](
+	resp *http.Response,
+	err error,
+	fn func(*T) error,
+) (*http.Response, error) {
+	if err != nil {
+		closeResponseBody(resp)
+		return nil, err
+	}
+	if resp == nil || resp.Body == nil {
+		return nil, ErrInvalidResp
+	}
+
+	defer resp.Body.Close()
+
+	decoder := json.NewDecoder(resp.Body)
+	token, err := decoder.Token()
+	if err == io.EOF {
+		return resp, nil
+	} else if err != nil {
+		return resp, err
+	} else if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return resp, fmt.Errorf("%w: the response body is not a JSON array", ErrInvalidResp)
+	}
+
+	for decoder.More() {
+		elem := new(T)
+		if err := decoder.Decode(elem); err != nil {
+			return resp, err
+		}
+		if err := fn(elem); err != nil {
+			return resp, err
+		}
+	}
+
+	if _, err := decoder.Token(); err != nil {
+		return resp, err
+	}
+
+	return resp, nil
+}
+
+// ToWriter copies the response body to the writer, and returns the number of bytes that are
+// copied. The method will close the body of the response that after read.
+//
+//	resp, err := request.GET("https://example.com/file")
+//	n, resp, err := request.ToWriter(resp, err, os.Stdout)
+func ToWriter(resp *http.Response, err error, w io.Writer) (int64, *http.Response, error) {
+	if err != nil {
+		closeResponseBody(resp)
+		return 0, nil, err
+	}
+	if resp == nil || resp.Body == nil {
+		return 0, nil, ErrInvalidResp
+	}
+
+	defer resp.Body.Close()
+
+	n, err := io.Copy(w, resp.Body)
+	return n, resp, err
+}
+
+// ToFile saves the response body into the file, and returns the number of bytes that are written.
+// The body is written into a temporary file in the same directory first, and the temporary file
+// will be renamed to the path after the whole body is written, so the file will not be changed if
+// it fails to read the body. The method will close the body of the response that after read.
+//
+//	resp, err := request.GET("https://example.com/file.zip", request.RequestOptions{
+//	  MaxBodySize: 100 << 20, // 100 MB
+//	  Timeout:     request.RequestTimeoutNoLimit,
+//	})
+//	n, resp, err := request.ToFile(resp, err, "file.zip")
+func ToFile(resp *http.Response, err error, path string) (int64, *http.Response, error) {
+	if err != nil {
+		closeResponseBody(resp)
+		return 0, nil, err
+	}
+	if resp == nil || resp.Body == nil {
+		return 0, nil, ErrInvalidResp
+	}
+
+	defer resp.Body.Close()
+
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return 0, resp, err
+	}
+	tmpPath := file.Name()
+
+	// the temporary file is only readable by the owner, so make it like a normally created file.
+	err = file.Chmod(0o644)
+	n := int64(0)
+	if err == nil {
+		n, err = io.Copy(file, resp.Body)
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return n, resp, err
+	}
+
+	return n, resp, nil
+}
+
+// limitedBody is the response body that fails with a `*BodyTooLargeError` error if it's larger
+// than the limit.
+type limitedBody struct {
+	io.ReadCloser
+	limit     int64
+	remaining int64
+}
+
+// Read reads the data from the underlying body, and it returns an error if the size of the data
+// exceeds the limit.
+func (body *limitedBody) Read(p []byte) (int, error) {
+	if body.remaining < 0 {
+		return 0, &BodyTooLargeError{Limit: body.limit}
+	}
+
+	// read one more byte to check whether there is more data than the limit.
+	if int64(len(p)) > body.remaining+1 {
+		p = p[:body.remaining+1]
+	}
+
+	n, err := body.ReadCloser.Read(p)
+	if int64(n) > body.remaining {
+		n = int(body.remaining)
+		body.remaining = -1
+		return n, &BodyTooLargeError{Limit: body.limit}
+	}
+	body.remaining -= int64(n)
+
+	return n, err
+}
@@ -0,0 +1,80 @@
+//go:build go1.23
+
+package request
+
+import (
+	"errors"
+	"iter"
+	"net/http"
+)
+
+// errStopStream is the error to stop decoding the stream when the loop is broken.
+var errStopStream = errors.New("stream stopped")
+
+// ToStreamSeq returns an iterator that decodes the response body as a JSON array element by
+// element like `ToStream`. The iterator yields the error as the last value if it fails to send the
+// request or decode the body. The body of the response will be closed after the iteration.
+//
+//	resp, err := request.GET("https://example.com/products")
+//	for product, err := range request.ToStreamSeq[Product](resp, err) {
+//	  if err != nil {
+//	    // Error handling
+//	  }
+//	  // Element handling
+//	}
+func ToStreamSeq[T any](resp *http.Response, err error) iter.Seq2[*T, error] {
+	return func(yield func(*T, error) bool) {
+		_, err := ToStream(resp, err, func(elem *T) error {
+			if !yield(elem, nil) {
+				return errStopStream
+			}
+			return nil
+		})
+		if err != nil && err != errStopStream {
+			yield(nil, err)
+		}
+	}
+}
+
+// ToNDJSONSeq returns an iterator that decodes the response body as the newline-delimited JSON
+// stream line by line like `ToNDJSON`. The `*LineError` of a line that can't be decoded is yielded
+// with a nil object, and the iteration continues with the next line if the loop isn't broken.
+// Other errors are yielded as the last value. The body of the response will be closed after the
+// iteration.
+//
+//	resp, err := request.GET("https://example.com/logs")
+//	for log, err := range request.ToNDJSONSeq[Log](resp, err) {
+//	  if err != nil {
+//	    // Error handling
+//	    continue
+//	  }
+//	  // Object handling
+//	}
+func ToNDJSONSeq[T any](
+	resp *http.Response,
+	err error,
+	opts ...NDJSONOptions,
+) iter.Seq2[*T, error] {
+	return func(yield func(*T, error) bool) {
+		var opt NDJSONOptions
+		if len(opts) > 0 {
+			opt = opts[0]
+		}
+		opt.OnLineError = func(err *LineError) error {
+			if !yield(nil, err) {
+				return errStopStream
+			}
+			return nil
+		}
+
+		_, err := ToNDJSON(resp, err, func(obj *T) error {
+			if !yield(obj, nil) {
+				return errStopStream
+			}
+			return nil
+		}, opt)
+		if err != nil && err != errStopStream {
+			yield(nil, err)
+		}
+	}
+}
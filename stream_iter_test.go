@@ -0,0 +1,76 @@
+//go:build go1.23
+
+package request
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ghosind/go-assert"
+)
+
+func TestToStreamSeq(t *testing.T) {
+	a := assert.New(t)
+
+	count := 0
+	seq := ToStreamSeq[testStreamElement](GET("http://127.0.0.1:8080/stream?count=100"))
+	for elem, err := range seq {
+		a.NilNow(err)
+		a.EqualNow(elem.ID, count)
+		count++
+	}
+	a.EqualNow(count, 100)
+
+	// break the loop
+	count = 0
+	for range ToStreamSeq[testStreamElement](GET("http://127.0.0.1:8080/stream?count=100")) {
+		count++
+		if count == 10 {
+			break
+		}
+	}
+	a.EqualNow(count, 10)
+
+	// yield the error as the last value
+	count = 0
+	var lastErr error
+	for _, err := range ToStreamSeq[testStreamElement](GET("http://127.0.0.1:8080/test")) {
+		count++
+		lastErr = err
+	}
+	a.EqualNow(count, 1)
+	a.TrueNow(errors.Is(lastErr, ErrInvalidResp))
+}
+
+func TestToNDJSONSeq(t *testing.T) {
+	a := assert.New(t)
+
+	// the invalid line is yielded as an error, and the iteration continues
+	count := 0
+	errCount := 0
+	resp, err := GET("http://127.0.0.1:8080/stream?ndjson=1&count=10&invalid=3")
+	for elem, err := range ToNDJSONSeq[testStreamElement](resp, err) {
+		if err != nil {
+			errCount++
+			var lineErr *LineError
+			a.TrueNow(errors.As(err, &lineErr))
+			a.EqualNow(lineErr.Line, 4)
+			continue
+		}
+		a.EqualNow(elem.ID, count)
+		count++
+	}
+	a.EqualNow(count, 10)
+	a.EqualNow(errCount, 1)
+
+	// break the loop
+	count = 0
+	resp, err = GET("http://127.0.0.1:8080/stream?ndjson=1&count=100")
+	for range ToNDJSONSeq[testStreamElement](resp, err) {
+		count++
+		if count == 10 {
+			break
+		}
+	}
+	a.EqualNow(count, 10)
+}
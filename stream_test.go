@@ -0,0 +1,178 @@
+package request
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ghosind/go-assert"
+)
+
+type testStreamElement struct {
+	ID int `json:"id"`
+}
+
+func TestToStream(t *testing.T) {
+	a := assert.New(t)
+
+	count := 0
+	resp, err := GET("http://127.0.0.1:8080/stream?count=1000")
+	resp, err = ToStream(resp, err, func(elem *testStreamElement) error {
+		a.EqualNow(elem.ID, count)
+		count++
+		return nil
+	})
+	a.NilNow(err)
+	a.NotNilNow(resp)
+	a.EqualNow(count, 1000)
+
+	// stop by the callback
+	stopErr := errors.New("stop")
+	count = 0
+	resp, err = GET("http://127.0.0.1:8080/stream?count=1000")
+	_, err = ToStream(resp, err, func(elem *testStreamElement) error {
+		count++
+		if count == 10 {
+			return stopErr
+		}
+		return nil
+	})
+	a.EqualNow(err, stopErr)
+	a.EqualNow(count, 10)
+
+	// empty array and empty body
+	resp, err = GET("http://127.0.0.1:8080/stream")
+	_, err = ToStream(resp, err, func(*testStreamElement) error {
+		t.Error("unexpected element")
+		return nil
+	})
+	a.NilNow(err)
+	resp, err = GET("http://127.0.0.1:8080/status?status=204")
+	_, err = ToStream(resp, err, func(*testStreamElement) error {
+		t.Error("unexpected element")
+		return nil
+	})
+	a.NilNow(err)
+}
+
+func TestToStreamWithInvalidBody(t *testing.T) {
+	a := assert.New(t)
+
+	resp, err := GET("http://127.0.0.1:8080/test")
+	_, err = ToStream(resp, err, func(*testResponse) error {
+		return nil
+	})
+	a.TrueNow(errors.Is(err, ErrInvalidResp))
+
+	resp, err = GET("http://127.0.0.1:8080/stream?count=10", RequestOptions{MaxBodySize: 20})
+	_, err = ToStream(resp, err, func(*testStreamElement) error {
+		return nil
+	})
+	var sizeErr *BodyTooLargeError
+	a.TrueNow(errors.As(err, &sizeErr))
+	a.EqualNow(sizeErr.Limit, int64(20))
+
+	requestErr := errors.New("request error")
+	resp, err = ToStream(nil, requestErr, func(*testStreamElement) error { return nil })
+	a.NilNow(resp)
+	a.EqualNow(err, requestErr)
+
+	_, err = ToStream[testStreamElement](nil, nil, nil)
+	a.EqualNow(err, ErrInvalidResp)
+}
+
+func TestToWriter(t *testing.T) {
+	a := assert.New(t)
+
+	buf := new(bytes.Buffer)
+	resp, err := GET("http://127.0.0.1:8080/stream?count=3")
+	n, resp, err := ToWriter(resp, err, buf)
+	a.NilNow(err)
+	a.NotNilNow(resp)
+	a.EqualNow(buf.String(), `[{"id":0},{"id":1},{"id":2}]`)
+	a.EqualNow(n, int64(buf.Len()))
+
+	_, _, err = ToWriter(nil, nil, buf)
+	a.EqualNow(err, ErrInvalidResp)
+}
+
+func TestToFile(t *testing.T) {
+	a := assert.New(t)
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "stream.json")
+
+	resp, err := GET("http://127.0.0.1:8080/stream?count=3")
+	n, _, err := ToFile(resp, err, path)
+	a.NilNow(err)
+	a.EqualNow(n, int64(28))
+
+	data, err := os.ReadFile(path)
+	a.NilNow(err)
+	a.EqualNow(string(data), `[{"id":0},{"id":1},{"id":2}]`)
+
+	// the file is not changed if the body is too large
+	resp, err = GET("http://127.0.0.1:8080/stream?count=100", RequestOptions{MaxBodySize: 100})
+	_, _, err = ToFile(resp, err, path)
+	var sizeErr *BodyTooLargeError
+	a.TrueNow(errors.As(err, &sizeErr))
+
+	data, err = os.ReadFile(path)
+	a.NilNow(err)
+	a.EqualNow(string(data), `[{"id":0},{"id":1},{"id":2}]`)
+
+	entries, err := os.ReadDir(dir)
+	a.NilNow(err)
+	a.EqualNow(len(entries), 1)
+
+	resp, err = GET("http://127.0.0.1:8080/test")
+	_, _, err = ToFile(resp, err, filepath.Join(dir, "none", "test"))
+	a.NotNilNow(err)
+}
+
+func TestMaxBodySize(t *testing.T) {
+	a := assert.New(t)
+
+	cli := New(Config{MaxBodySize: 28})
+
+	content, _, err := ToString(cli.GET("http://127.0.0.1:8080/stream?count=3"))
+	a.NilNow(err)
+	a.EqualNow(content, `[{"id":0},{"id":1},{"id":2}]`)
+
+	content, _, err = ToString(cli.GET("http://127.0.0.1:8080/stream?count=4"))
+	a.TrueNow(errors.As(err, new(*BodyTooLargeError)))
+	a.EqualNow(content, "")
+	a.EqualNow(err.Error(), "response body exceeds the limit of 28 bytes")
+
+	// the request options overwrite the client config
+	_, _, err = ToString(cli.GET("http://127.0.0.1:8080/stream?count=4", RequestOptions{
+		MaxBodySize: 1000,
+	}))
+	a.NilNow(err)
+
+	_, _, err = ToString(Req("http://127.0.0.1:8080/stream?count=4").SetMaxBodySize(10).Do())
+	a.TrueNow(errors.As(err, new(*BodyTooLargeError)))
+}
+
+func TestResponseContextCanceledOnClose(t *testing.T) {
+	a := assert.New(t)
+
+	resp, err := GET("http://127.0.0.1:8080/stream?count=10000")
+	a.NilNow(err)
+
+	// the body is still readable after the request returned
+	ctx := resp.Request.Context()
+	a.NilNow(ctx.Err())
+
+	count := 0
+	_, err = ToStream(resp, err, func(*testStreamElement) error {
+		count++
+		return nil
+	})
+	a.NilNow(err)
+	a.EqualNow(count, 10000)
+	a.TrueNow(errors.Is(ctx.Err(), context.Canceled))
+}
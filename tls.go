@@ -0,0 +1,318 @@
+package request
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// TLSConfig defines the TLS settings for the requests, like the custom root CAs, the client
+// certificates for mutual TLS, and the pinned public keys.
+//
+//	cli := request.New(request.Config{
+//	  TLS: &request.TLSConfig{
+//	    RootCAFiles: []string{"/etc/ssl/private-ca.pem"},
+//	    Certificates: []request.TLSCertificate{
+//	      {CertFile: "client.crt", KeyFile: "client.key"},
+//	    },
+//	    MinVersion: tls.VersionTLS12,
+//	  },
+//	})
+//
+// The files are read when the transport for the settings is created, and the transport is cached
+// by the client. The key of the settings is also cached for the same `*TLSConfig`, so the changes
+// of the settings or the files will not be applied to a used `*TLSConfig`. To apply the rotated
+// files, set a new `*TLSConfig` to the client or the request options.
+type TLSConfig struct {
+	// InsecureSkipVerify controls whether the HTTP client verifies the server's certificate and
+	// host name. The pinned public keys will still be checked if they are set.
+	InsecureSkipVerify bool
+	// RootCAs are the PEM-encoded certificates of the root certificate authorities to verify the
+	// server's certificate. It'll use the host's root CA set if no root CAs and root CA files are
+	// set.
+	RootCAs [][]byte
+	// RootCAFiles are the paths of the PEM-encoded certificate files of the root certificate
+	// authorities to verify the server's certificate.
+	RootCAFiles []string
+	// Certificates are the client certificates to present to the server for mutual TLS.
+	Certificates []TLSCertificate
+	// ServerName is used to verify the hostname of the server's certificate, and it's also
+	// included in the handshake to support virtual hosting. Default is the host of the request.
+	ServerName string
+	// MinVersion is the minimum TLS version that is acceptable, for example, `tls.VersionTLS12`.
+	MinVersion uint16
+	// MaxVersion is the maximum TLS version that is acceptable, for example, `tls.VersionTLS13`.
+	MaxVersion uint16
+	// CipherSuites is a list of enabled TLS 1.0-1.2 cipher suites, and it'll use a default list if
+	// it's empty.
+	CipherSuites []uint16
+	// PinnedPublicKeys are the SHA-256 hashes of the Subject Public Key Info of the trusted
+	// certificates, in base64 (with or without the "sha256/" prefix) or hex encoding. The
+	// connection will fail with an `ErrPublicKeyNotPinned` error if no certificate in the chain
+	// of the server matches any pinned key.
+	PinnedPublicKeys []string
+}
+
+// TLSCertificate is a client certificate and its private key, which can be PEM-encoded data or
+// the paths of the PEM-encoded files.
+type TLSCertificate struct {
+	// CertPEM is the PEM-encoded certificate.
+	CertPEM []byte
+	// KeyPEM is the PEM-encoded private key.
+	KeyPEM []byte
+	// CertFile is the path of the PEM-encoded certificate file, it'll be ignored if the CertPEM is
+	// set.
+	CertFile string
+	// KeyFile is the path of the PEM-encoded private key file, it'll be ignored if the KeyPEM is
+	// set.
+	KeyFile string
+}
+
+// getTLSOptions gets the TLS settings from the request options or the client config, the
+// request options' TLS settings will overwrite the client's settings. It returns nil if no TLS
+// settings are set.
+func (cli *Client) getTLSOptions(opt RequestOptions) *TLSConfig {
+	cfg := opt.TLS
+	if cfg == nil {
+		cfg = cli.TLS
+	}
+
+	if !opt.InsecureSkipVerify {
+		return cfg
+	}
+
+	merged := new(TLSConfig)
+	if cfg != nil {
+		*merged = *cfg
+	}
+	merged.InsecureSkipVerify = true
+
+	return merged
+}
+
+// maxCachedTLSKeys is the maximum number of the cached keys of the TLS settings, and the cached
+// keys will be cleared when the limitation is reached.
+const maxCachedTLSKeys = 1024
+
+// getTLSKey returns the key of the TLS settings of the request, and the caller must hold the
+// transport lock. The key of a `*TLSConfig` is cached by the client, so it'll not be calculated
+// for every request.
+func (cli *Client) getTLSKey(opt RequestOptions) (string, error) {
+	options := opt.TLS
+	if options == nil {
+		options = cli.TLS
+	}
+
+	key, ok := cli.tlsKeys[options]
+	if !ok {
+		var err error
+		key, err = options.key()
+		if err != nil {
+			return "", err
+		}
+
+		if cli.tlsKeys == nil || len(cli.tlsKeys) >= maxCachedTLSKeys {
+			cli.tlsKeys = make(map[*TLSConfig]string)
+		}
+		cli.tlsKeys[options] = key
+	}
+
+	if opt.InsecureSkipVerify {
+		key += "+insecure"
+	}
+
+	return key, nil
+}
+
+// key returns a string that identifies the TLS settings, and the same settings have the same key.
+// The sizes and the modification times of the files are also included, so the changed files have
+// a different key.
+func (options *TLSConfig) key() (string, error) {
+	if options == nil {
+		return "", nil
+	}
+
+	files := make([]string, 0, len(options.RootCAFiles)+len(options.Certificates)*2)
+	for _, file := range options.RootCAFiles {
+		files = append(files, getFileStamp(file))
+	}
+	for _, cert := range options.Certificates {
+		files = append(files, getFileStamp(cert.CertFile), getFileStamp(cert.KeyFile))
+	}
+
+	data, err := json.Marshal(struct {
+		Config *TLSConfig
+		Files  []string
+	}{options, files})
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// getFileStamp returns a string with the path, the size, and the modification time of the file.
+func getFileStamp(path string) string {
+	if path == "" {
+		return ""
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return path
+	}
+
+	return fmt.Sprintf("%s:%d:%d", path, info.Size(), info.ModTime().UnixNano())
+}
+
+// build creates a new `tls.Config` by the TLS settings.
+func (options *TLSConfig) build() (*tls.Config, error) {
+	cfg := new(tls.Config)
+
+	cfg.InsecureSkipVerify = options.InsecureSkipVerify
+	cfg.ServerName = options.ServerName
+	cfg.MinVersion = options.MinVersion
+	cfg.MaxVersion = options.MaxVersion
+	if len(options.CipherSuites) > 0 {
+		cfg.CipherSuites = append([]uint16{}, options.CipherSuites...)
+	}
+
+	rootCAs, err := options.loadRootCAs()
+	if err != nil {
+		return nil, err
+	}
+	cfg.RootCAs = rootCAs
+
+	for _, cert := range options.Certificates {
+		certificate, err := cert.load()
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = append(cfg.Certificates, certificate)
+	}
+
+	if len(options.PinnedPublicKeys) > 0 {
+		pins, err := parsePinnedPublicKeys(options.PinnedPublicKeys)
+		if err != nil {
+			return nil, err
+		}
+		insecure := cfg.InsecureSkipVerify
+		cfg.VerifyConnection = func(state tls.ConnectionState) error {
+			return verifyPinnedPublicKeys(state, pins, insecure)
+		}
+	}
+
+	return cfg, nil
+}
+
+// loadRootCAs creates a certificate pool with the root CAs, and it returns nil if no root CAs are
+// set.
+func (options *TLSConfig) loadRootCAs() (*x509.CertPool, error) {
+	if len(options.RootCAs) == 0 && len(options.RootCAFiles) == 0 {
+		return nil, nil
+	}
+
+	pool := x509.NewCertPool()
+
+	for _, ca := range options.RootCAs {
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("%w: no certificate found in root CA", ErrInvalidCertificate)
+		}
+	}
+
+	for _, file := range options.RootCAFiles {
+		ca, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("%w: no certificate found in %s", ErrInvalidCertificate, file)
+		}
+	}
+
+	return pool, nil
+}
+
+// load parses the certificate and the private key.
+func (cert TLSCertificate) load() (tls.Certificate, error) {
+	certPEM := cert.CertPEM
+	if len(certPEM) == 0 && cert.CertFile != "" {
+		data, err := os.ReadFile(cert.CertFile)
+		if err != nil {
+			return tls.Certificate{}, err
+		}
+		certPEM = data
+	}
+
+	keyPEM := cert.KeyPEM
+	if len(keyPEM) == 0 && cert.KeyFile != "" {
+		data, err := os.ReadFile(cert.KeyFile)
+		if err != nil {
+			return tls.Certificate{}, err
+		}
+		keyPEM = data
+	}
+
+	certificate, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("%w: %v", ErrInvalidCertificate, err)
+	}
+
+	return certificate, nil
+}
+
+// parsePinnedPublicKeys decodes the pinned public key hashes.
+func parsePinnedPublicKeys(keys []string) ([][]byte, error) {
+	pins := make([][]byte, 0, len(keys))
+
+	for _, key := range keys {
+		key = strings.TrimPrefix(strings.TrimSpace(key), "sha256/")
+
+		pin, err := base64.StdEncoding.DecodeString(key)
+		if err != nil || len(pin) != sha256.Size {
+			pin, err = hex.DecodeString(key)
+		}
+		if err != nil || len(pin) != sha256.Size {
+			return nil, fmt.Errorf("%w: invalid pinned public key %q", ErrInvalidCertificate, key)
+		}
+
+		pins = append(pins, pin)
+	}
+
+	return pins, nil
+}
+
+// verifyPinnedPublicKeys checks whether any certificate in the verified chains of the server
+// matches the pinned public keys. The extra certificates sent by the server are not trusted, and
+// only the leaf certificate is checked if the chains are not verified.
+func verifyPinnedPublicKeys(state tls.ConnectionState, pins [][]byte, insecure bool) error {
+	certs := make([]*x509.Certificate, 0)
+	if insecure {
+		if len(state.PeerCertificates) > 0 {
+			certs = append(certs, state.PeerCertificates[0])
+		}
+	} else {
+		for _, chain := range state.VerifiedChains {
+			certs = append(certs, chain...)
+		}
+	}
+
+	for _, cert := range certs {
+		hash := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+		for _, pin := range pins {
+			if string(hash[:]) == string(pin) {
+				return nil
+			}
+		}
+	}
+
+	return ErrPublicKeyNotPinned
+}
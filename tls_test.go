@@ -0,0 +1,312 @@
+package request
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ghosind/go-assert"
+)
+
+type testCertificate struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+func newTestCertificate(
+	a *assert.Assertion,
+	template *x509.Certificate,
+	parent *testCertificate,
+) *testCertificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	a.NilNow(err)
+
+	parentCert, parentKey := template, key
+	if parent != nil {
+		parentCert, parentKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parentCert, &key.PublicKey, parentKey)
+	a.NilNow(err)
+	cert, err := x509.ParseCertificate(der)
+	a.NilNow(err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	a.NilNow(err)
+
+	return &testCertificate{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+func newTestTLSServer(a *assert.Assertion) (*httptest.Server, *testCertificate, *testCertificate) {
+	ca := newTestCertificate(a, &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "go-request test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+	}, nil)
+	server := newTestCertificate(a, &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, ca)
+	client := newTestCertificate(a, &x509.Certificate{
+		SerialNumber: big.NewInt(3),
+		Subject:      pkix.Name{CommonName: "client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, ca)
+
+	serverCert, err := tls.X509KeyPair(server.certPEM, server.keyPEM)
+	a.NilNow(err)
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.cert)
+
+	handler := func(rw http.ResponseWriter, r *http.Request) {
+		rw.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(handler))
+	ts.TLS = &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+	}
+	ts.StartTLS()
+
+	return ts, ca, client
+}
+
+func TestMutualTLS(t *testing.T) {
+	a := assert.New(t)
+
+	ts, ca, client := newTestTLSServer(a)
+	defer ts.Close()
+
+	cli := New(Config{
+		TLS: &TLSConfig{
+			RootCAs: [][]byte{ca.certPEM},
+			Certificates: []TLSCertificate{
+				{CertPEM: client.certPEM, KeyPEM: client.keyPEM},
+			},
+			MinVersion: tls.VersionTLS12,
+		},
+	})
+
+	content, _, err := ToString(cli.GET(ts.URL))
+	a.NilNow(err)
+	a.EqualNow(content, "client")
+
+	// without client certificate
+	_, err = cli.GET(ts.URL, RequestOptions{
+		TLS: &TLSConfig{RootCAs: [][]byte{ca.certPEM}},
+	})
+	a.NotNilNow(err)
+
+	// unknown authority
+	_, err = New().GET(ts.URL)
+	a.NotNilNow(err)
+
+	// with files
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	certFile := filepath.Join(dir, "client.crt")
+	keyFile := filepath.Join(dir, "client.key")
+	a.NilNow(os.WriteFile(caFile, ca.certPEM, 0600))
+	a.NilNow(os.WriteFile(certFile, client.certPEM, 0600))
+	a.NilNow(os.WriteFile(keyFile, client.keyPEM, 0600))
+
+	content, _, err = ToString(Req(ts.URL).
+		SetTLSConfig(TLSConfig{
+			RootCAFiles:  []string{caFile},
+			Certificates: []TLSCertificate{{CertFile: certFile, KeyFile: keyFile}},
+		}).
+		Do())
+	a.NilNow(err)
+	a.EqualNow(content, "client")
+}
+
+func TestPinnedPublicKeys(t *testing.T) {
+	a := assert.New(t)
+
+	ts, ca, client := newTestTLSServer(a)
+	defer ts.Close()
+
+	hash := sha256.Sum256(ca.cert.RawSubjectPublicKeyInfo)
+	certs := []TLSCertificate{{CertPEM: client.certPEM, KeyPEM: client.keyPEM}}
+
+	_, err := GET(ts.URL, RequestOptions{
+		TLS: &TLSConfig{
+			RootCAs:          [][]byte{ca.certPEM},
+			Certificates:     certs,
+			PinnedPublicKeys: []string{"sha256/" + base64.StdEncoding.EncodeToString(hash[:])},
+		},
+	})
+	a.NilNow(err)
+
+	otherHash := sha256.Sum256(client.cert.RawSubjectPublicKeyInfo)
+	_, err = GET(ts.URL, RequestOptions{
+		TLS: &TLSConfig{
+			InsecureSkipVerify: true,
+			Certificates:       certs,
+			PinnedPublicKeys:   []string{base64.StdEncoding.EncodeToString(otherHash[:])},
+		},
+	})
+	a.NotNilNow(err)
+	a.TrueNow(errors.Is(err, ErrPublicKeyNotPinned))
+}
+
+func TestPinnedPublicKeysWithExtraCertificate(t *testing.T) {
+	a := assert.New(t)
+
+	ts, ca, _ := newTestTLSServer(a)
+	ts.Close()
+
+	// the pinned certificate is sent by the server, but it's not in the verified chain
+	pinned := newTestCertificate(a, &x509.Certificate{
+		SerialNumber: big.NewInt(4),
+		Subject:      pkix.Name{CommonName: "pinned"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}, nil)
+	serverCert := ts.TLS.Certificates[0]
+	serverCert.Certificate = append(serverCert.Certificate, pinned.cert.Raw)
+
+	handler := func(rw http.ResponseWriter, r *http.Request) {}
+	ts = httptest.NewUnstartedServer(http.HandlerFunc(handler))
+	ts.TLS = &tls.Config{Certificates: []tls.Certificate{serverCert}}
+	ts.StartTLS()
+	defer ts.Close()
+
+	hash := sha256.Sum256(pinned.cert.RawSubjectPublicKeyInfo)
+	pins := []string{base64.StdEncoding.EncodeToString(hash[:])}
+
+	_, err := GET(ts.URL, RequestOptions{
+		TLS: &TLSConfig{RootCAs: [][]byte{ca.certPEM}, PinnedPublicKeys: pins},
+	})
+	a.TrueNow(errors.Is(err, ErrPublicKeyNotPinned))
+
+	// only the leaf certificate is checked without verification
+	_, err = GET(ts.URL, RequestOptions{
+		TLS: &TLSConfig{InsecureSkipVerify: true, PinnedPublicKeys: pins},
+	})
+	a.TrueNow(errors.Is(err, ErrPublicKeyNotPinned))
+
+	leafHash := sha256.Sum256(ts.Certificate().RawSubjectPublicKeyInfo)
+	resp, err := GET(ts.URL, RequestOptions{
+		TLS: &TLSConfig{
+			InsecureSkipVerify: true,
+			PinnedPublicKeys:   []string{base64.StdEncoding.EncodeToString(leafHash[:])},
+		},
+	})
+	a.NilNow(err)
+	resp.Body.Close()
+}
+
+func TestInvalidTLSConfig(t *testing.T) {
+	a := assert.New(t)
+
+	for _, cfg := range []TLSConfig{
+		{RootCAs: [][]byte{[]byte("invalid")}},
+		{RootCAFiles: []string{"not-exists.pem"}},
+		{Certificates: []TLSCertificate{{CertPEM: []byte("invalid"), KeyPEM: []byte("invalid")}}},
+		{Certificates: []TLSCertificate{{CertFile: "not-exists.crt"}}},
+		{PinnedPublicKeys: []string{"invalid"}},
+	} {
+		cfg := cfg
+		_, err := GET("https://localhost:8443", RequestOptions{TLS: &cfg})
+		a.NotNilNow(err)
+	}
+}
+
+func TestGetTLSOptions(t *testing.T) {
+	a := assert.New(t)
+	cli := New(Config{
+		TLS: &TLSConfig{ServerName: "example.com"},
+	})
+
+	a.EqualNow(cli.getTLSOptions(RequestOptions{}), cli.TLS)
+	a.EqualNow(cli.getTLSOptions(RequestOptions{
+		TLS: &TLSConfig{ServerName: "example.org"},
+	}).ServerName, "example.org")
+
+	options := cli.getTLSOptions(RequestOptions{InsecureSkipVerify: true})
+	a.TrueNow(options.InsecureSkipVerify)
+	a.EqualNow(options.ServerName, "example.com")
+	a.NotTrueNow(cli.TLS.InsecureSkipVerify)
+
+	a.NilNow(New().getTLSOptions(RequestOptions{}))
+	key, err := cli.TLS.key()
+	a.NilNow(err)
+	otherKey, err := (&TLSConfig{ServerName: "example.com"}).key()
+	a.NilNow(err)
+	a.EqualNow(key, otherKey)
+}
+
+func TestTLSKey(t *testing.T) {
+	a := assert.New(t)
+	cli := New()
+
+	ts, ca, _ := newTestTLSServer(a)
+	ts.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	a.NilNow(os.WriteFile(caFile, ca.certPEM, 0600))
+
+	options := &TLSConfig{RootCAFiles: []string{caFile}}
+	key, err := cli.getTLSKey(RequestOptions{TLS: options})
+	a.NilNow(err)
+
+	// the key of the same config is cached
+	options.ServerName = "example.com"
+	cachedKey, err := cli.getTLSKey(RequestOptions{TLS: options})
+	a.NilNow(err)
+	a.EqualNow(cachedKey, key)
+
+	insecureKey, err := cli.getTLSKey(RequestOptions{TLS: options, InsecureSkipVerify: true})
+	a.NilNow(err)
+	a.NotEqualNow(insecureKey, key)
+
+	// the rotated file has a different key
+	sameKey, err := cli.getTLSKey(RequestOptions{TLS: &TLSConfig{RootCAFiles: []string{caFile}}})
+	a.NilNow(err)
+	a.EqualNow(sameKey, key)
+
+	a.NilNow(os.WriteFile(caFile, append(ca.certPEM, ca.certPEM...), 0600))
+	rotatedKey, err := cli.getTLSKey(RequestOptions{
+		TLS: &TLSConfig{RootCAFiles: []string{caFile}},
+	})
+	a.NilNow(err)
+	a.NotEqualNow(rotatedKey, key)
+}
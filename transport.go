@@ -0,0 +1,166 @@
+package request
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// ConnectionPoolConfig defines the settings of the connection pool for the client's transports.
+//
+//	cli := request.New(request.Config{
+//	  ConnectionPool: &request.ConnectionPoolConfig{
+//	    MaxIdleConns:        100,
+//	    MaxIdleConnsPerHost: 10,
+//	    IdleConnTimeout:     90 * time.Second,
+//	  },
+//	})
+type ConnectionPoolConfig struct {
+	// MaxIdleConns controls the maximum number of idle connections across all hosts, default 100.
+	MaxIdleConns int
+	// MaxIdleConnsPerHost controls the maximum idle connections to keep per host, default 2.
+	MaxIdleConnsPerHost int
+	// MaxConnsPerHost limits the total number of connections per host, including connections in
+	// the dialing, active, and idle states, default no limit.
+	MaxConnsPerHost int
+	// IdleConnTimeout is the maximum amount of time an idle connection will remain idle before
+	// closing itself, default 90 seconds.
+	IdleConnTimeout time.Duration
+	// KeepAlive specifies the interval between keep-alive probes for an active network
+	// connection, default 30 seconds. Network protocols or operating systems that do not support
+	// keep-alive ignore this field. If negative, keep-alive probes are disabled.
+	KeepAlive time.Duration
+	// DisableKeepAlives disables HTTP keep-alives and will only use the connection to the server
+	// for a single HTTP request if it's true.
+	DisableKeepAlives bool
+}
+
+// maxCachedTransports is the maximum number of the cached transports of a client.
+const maxCachedTransports = 64
+
+// transportKey is the key of the cached transports, and the requests with the same proxy and TLS
+// settings share the same transport.
+type transportKey struct {
+	// proxy is the URL of the proxy server.
+	proxy string
+	// tls is the key of the TLS settings.
+	tls string
+}
+
+// CloseIdleConnections closes any connections on the client's transports which were previously
+// connected from previous requests but are now sitting idle in a "keep-alive" state. It does not
+// interrupt any connections currently in use.
+func (cli *Client) CloseIdleConnections() {
+	cli.transportMutex.Lock()
+	defer cli.transportMutex.Unlock()
+
+	for _, transport := range cli.transports {
+		transport.CloseIdleConnections()
+	}
+}
+
+// getTransport gets the transport by the request options. The transports are cached by the proxy
+// and TLS settings, so the requests with the same settings will reuse the connections. A cached
+// transport will be removed if the number of the transports reaches the limitation.
+func (cli *Client) getTransport(opt RequestOptions) (http.RoundTripper, error) {
+	proxyUrl := cli.getProxyURL(opt)
+	tlsOptions := cli.getTLSOptions(opt)
+
+	cli.transportMutex.Lock()
+	defer cli.transportMutex.Unlock()
+
+	tlsKey, err := cli.getTLSKey(opt)
+	if err != nil {
+		return nil, err
+	}
+	key := transportKey{
+		tls: tlsKey,
+	}
+	if proxyUrl != nil {
+		key.proxy = proxyUrl.String()
+	}
+
+	if transport, ok := cli.transports[key]; ok {
+		return transport, nil
+	}
+
+	var tlsConfig *tls.Config
+	if tlsOptions != nil {
+		cfg, err := tlsOptions.build()
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig = cfg
+	}
+
+	transport := cli.newTransport(proxyUrl, tlsConfig)
+	if cli.transports == nil {
+		cli.transports = make(map[transportKey]*http.Transport)
+	} else if len(cli.transports) >= maxCachedTransports {
+		cli.evictTransport()
+	}
+	cli.transports[key] = transport
+
+	return transport, nil
+}
+
+// evictTransport removes a cached transport and closes its idle connections to make room for a
+// new transport, and the caller must hold the transport lock. The requests that are using the
+// removed transport will not be affected.
+func (cli *Client) evictTransport() {
+	for key, transport := range cli.transports {
+		if key == (transportKey{}) {
+			// keep the default transport.
+			continue
+		}
+
+		delete(cli.transports, key)
+		transport.CloseIdleConnections()
+		return
+	}
+}
+
+// newTransport creates a new transport with the proxy, the TLS config, and the connection pool
+// settings of the client. It'll try to get the proxy from the environment variables if no proxy
+// is set.
+func (cli *Client) newTransport(proxyUrl *url.URL, tlsConfig *tls.Config) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if proxyUrl != nil {
+		transport.Proxy = http.ProxyURL(proxyUrl)
+	} else {
+		transport.Proxy = http.ProxyFromEnvironment
+	}
+
+	if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	if pool := cli.ConnectionPool; pool != nil {
+		if pool.MaxIdleConns > 0 {
+			transport.MaxIdleConns = pool.MaxIdleConns
+		}
+		if pool.MaxIdleConnsPerHost > 0 {
+			transport.MaxIdleConnsPerHost = pool.MaxIdleConnsPerHost
+		}
+		if pool.MaxConnsPerHost > 0 {
+			transport.MaxConnsPerHost = pool.MaxConnsPerHost
+		}
+		if pool.IdleConnTimeout > 0 {
+			transport.IdleConnTimeout = pool.IdleConnTimeout
+		}
+		transport.DisableKeepAlives = pool.DisableKeepAlives
+
+		if pool.KeepAlive != 0 {
+			dialer := &net.Dialer{
+				Timeout:   30 * time.Second,
+				KeepAlive: pool.KeepAlive,
+			}
+			transport.DialContext = dialer.DialContext
+		}
+	}
+
+	return transport
+}
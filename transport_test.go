@@ -0,0 +1,121 @@
+package request
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ghosind/go-assert"
+)
+
+func TestGetTransport(t *testing.T) {
+	a := assert.New(t)
+	cli := New()
+
+	transport, err := cli.getTransport(RequestOptions{})
+	a.NilNow(err)
+	a.NotNilNow(transport)
+	a.EqualNow(mustGetTransport(a, cli, RequestOptions{}), transport)
+
+	proxyOpt := RequestOptions{
+		Proxy: &ProxyConfig{Protocol: "http", Host: "127.0.0.1", Port: "8000"},
+	}
+	proxyTransport := mustGetTransport(a, cli, proxyOpt)
+	a.NotEqualNow(proxyTransport, transport)
+	a.EqualNow(mustGetTransport(a, cli, proxyOpt), proxyTransport)
+
+	insecureTransport := mustGetTransport(a, cli, RequestOptions{InsecureSkipVerify: true})
+	a.NotEqualNow(insecureTransport, transport)
+	a.NotEqualNow(insecureTransport, proxyTransport)
+	a.TrueNow(insecureTransport.(*http.Transport).TLSClientConfig.InsecureSkipVerify)
+
+	a.EqualNow(len(cli.transports), 3)
+
+	tlsTransport := mustGetTransport(a, cli, RequestOptions{
+		TLS: &TLSConfig{ServerName: "example.com"},
+	})
+	a.EqualNow(mustGetTransport(a, cli, RequestOptions{
+		TLS: &TLSConfig{ServerName: "example.com"},
+	}), tlsTransport)
+	a.EqualNow(len(cli.transports), 4)
+
+	_, err = cli.getTransport(RequestOptions{
+		TLS: &TLSConfig{RootCAs: [][]byte{[]byte("invalid")}},
+	})
+	a.NotNilNow(err)
+}
+
+func TestGetTransportLimit(t *testing.T) {
+	a := assert.New(t)
+	cli := New()
+
+	transport := mustGetTransport(a, cli, RequestOptions{})
+	for i := 0; i < maxCachedTransports*2; i++ {
+		mustGetTransport(a, cli, RequestOptions{
+			TLS: &TLSConfig{ServerName: strconv.Itoa(i)},
+		})
+	}
+	a.EqualNow(len(cli.transports), maxCachedTransports)
+
+	// the default transport is kept
+	a.EqualNow(mustGetTransport(a, cli, RequestOptions{}), transport)
+}
+
+func mustGetTransport(a *assert.Assertion, cli *Client, opt RequestOptions) http.RoundTripper {
+	transport, err := cli.getTransport(opt)
+	a.NilNow(err)
+	return transport
+}
+
+func TestConnectionPool(t *testing.T) {
+	a := assert.New(t)
+	cli := New(Config{
+		ConnectionPool: &ConnectionPoolConfig{
+			MaxIdleConns:        10,
+			MaxIdleConnsPerHost: 5,
+			MaxConnsPerHost:     20,
+			IdleConnTimeout:     time.Minute,
+			KeepAlive:           time.Minute,
+			DisableKeepAlives:   true,
+		},
+	})
+
+	transport := mustGetTransport(a, cli, RequestOptions{}).(*http.Transport)
+	a.EqualNow(transport.MaxIdleConns, 10)
+	a.EqualNow(transport.MaxIdleConnsPerHost, 5)
+	a.EqualNow(transport.MaxConnsPerHost, 20)
+	a.EqualNow(transport.IdleConnTimeout, time.Minute)
+	a.TrueNow(transport.DisableKeepAlives)
+
+	cli = New(Config{
+		ConnectionPool: &ConnectionPoolConfig{
+			MaxConnsPerHost: 20,
+		},
+	})
+	transport = mustGetTransport(a, cli, RequestOptions{}).(*http.Transport)
+	defaultTransport := http.DefaultTransport.(*http.Transport)
+	a.EqualNow(transport.MaxIdleConns, defaultTransport.MaxIdleConns)
+	a.EqualNow(transport.IdleConnTimeout, defaultTransport.IdleConnTimeout)
+	a.EqualNow(transport.MaxConnsPerHost, 20)
+}
+
+func TestCloseIdleConnections(t *testing.T) {
+	a := assert.New(t)
+	cli := New()
+
+	// nothing to close
+	cli.CloseIdleConnections()
+
+	_, err := cli.GET("http://localhost:8080")
+	a.NilNow(err)
+	_, err = cli.GET("http://localhost:8080", RequestOptions{
+		Proxy: &ProxyConfig{Protocol: "http", Host: "127.0.0.1", Port: "8000"},
+	})
+	a.NilNow(err)
+
+	cli.CloseIdleConnections()
+
+	_, err = cli.GET("http://localhost:8080")
+	a.NilNow(err)
+}
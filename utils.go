@@ -1,6 +1,11 @@
 package request
 
-import "strings"
+import (
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
 
 // getResponseType gets the type of the response's content from the `Content-Type` field in the
 // response headers.
@@ -10,9 +15,70 @@ func getContentType(contentType string) string {
 	switch {
 	case strings.Contains(contentType, "json"):
 		return RequestContentTypeJSON
+	case strings.Contains(contentType, "xml"):
+		return RequestContentTypeXML
 	case contentType == "":
 		return RequestContentTypeJSON
 	default:
 		return "unknown"
 	}
 }
+
+// closeRequestBody closes the request body if it is closable, to release the resources like the
+// goroutine that is writing into a pipe.
+func closeRequestBody(body io.Reader) {
+	if closer, ok := body.(io.Closer); ok {
+		closer.Close()
+	}
+}
+
+// closeResponseBody closes the body of the response if the response is not nil, it's used to
+// release the resources of the response that is returned with an error.
+func closeResponseBody(resp *http.Response) {
+	if resp != nil && resp.Body != nil {
+		resp.Body.Close()
+	}
+}
+
+// callOnBodyClose wraps the response body to call the function once when the body is closed, or
+// calls the function immediately if there is no response body.
+func callOnBodyClose(resp *http.Response, fn func()) {
+	if resp == nil || resp.Body == nil {
+		fn()
+		return
+	}
+
+	body := &onCloseBody{ReadCloser: resp.Body, fn: fn}
+	if rwc, ok := resp.Body.(io.ReadWriteCloser); ok {
+		// keep the body writable for the responses of the protocol switching.
+		resp.Body = &onCloseReadWriteBody{onCloseBody: body, writer: rwc}
+	} else {
+		resp.Body = body
+	}
+}
+
+// onCloseBody is the response body that calls the function once when it's closed.
+type onCloseBody struct {
+	io.ReadCloser
+	fn   func()
+	once sync.Once
+}
+
+// Close closes the body and calls the function.
+func (body *onCloseBody) Close() error {
+	err := body.ReadCloser.Close()
+	body.once.Do(body.fn)
+	return err
+}
+
+// onCloseReadWriteBody is the writable response body that calls the function once when it's
+// closed.
+type onCloseReadWriteBody struct {
+	*onCloseBody
+	writer io.Writer
+}
+
+// Write writes the data to the underlying body.
+func (body *onCloseReadWriteBody) Write(p []byte) (int, error) {
+	return body.writer.Write(p)
+}
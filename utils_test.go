@@ -12,5 +12,8 @@ func TestGetContentType(t *testing.T) {
 	a.Equal(getContentType(""), "json")
 	a.Equal(getContentType("application/json"), "json")
 	a.Equal(getContentType("application/json; charset=utf8"), "json")
-	a.Equal(getContentType("application/xml; charset=utf8"), "unknown")
+	a.Equal(getContentType("application/xml; charset=utf8"), "xml")
+	a.Equal(getContentType("text/xml"), "xml")
+	a.Equal(getContentType("application/rss+xml"), "xml")
+	a.Equal(getContentType("text/html"), "unknown")
 }
@@ -1,14 +1,36 @@
 package request
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/json"
 	"io"
 	"net/http"
 )
 
+// NDJSONOptions is the options for decoding the newline-delimited JSON stream.
+type NDJSONOptions struct {
+	// MaxLineSize is the maximum number of bytes of a line including the line break, default 1 MB.
+	// A line that is longer than the limit is reported as a `*LineError` with the
+	// `bufio.ErrTooLong` error, and it stops the decoding because the rest of the line can't be
+	// skipped.
+	MaxLineSize int
+	// OnLineError is the function to handle the `*LineError` of the lines that can't be decoded.
+	// The line will be skipped if the function returns nil, and the decoding stops with the
+	// returned error otherwise. The decoding stops with the `*LineError` if the function is not
+	// set.
+	OnLineError func(err *LineError) error
+}
+
+// defaultNDJSONMaxLineSize is the default maximum number of bytes of a line in the
+// newline-delimited JSON stream.
+const defaultNDJSONMaxLineSize = 1 << 20
+
 // ToObject reads data from the response body and tries to decode it to an object as the parameter
-// type. It'll read the encoding type from the 'Content-Type' field in the response header. The
-// method will close the body of the response that after read.
+// type. It'll read the encoding type from the 'Content-Type' field in the response header, and
+// decode the body by the codec that handles the MIME type, for example, as a XML if the content
+// type is `application/xml` or `text/xml`. It'll try to decode the body as a JSON if no codec can
+// handle the content type. The method will close the body of the response that after read.
 //
 //	data, resp, err := request.ToObject[SomeStructType](request.Request("https://example.com"))
 //	if err != nil {
@@ -17,6 +39,7 @@ import (
 //	// Data or response handling
 func ToObject[T any](resp *http.Response, err error) (*T, *http.Response, error) {
 	if err != nil {
+		closeResponseBody(resp)
 		return nil, nil, err
 	}
 	if resp == nil || resp.Body == nil {
@@ -34,12 +57,10 @@ func ToObject[T any](resp *http.Response, err error) (*T, *http.Response, error)
 
 	out := new(T)
 
-	contentType := resp.Header.Get("Content-Type")
-	switch getContentType(contentType) {
-	default:
-		if err := json.Unmarshal(data, &out); err != nil {
-			return nil, resp, err
-		}
+	cli := getClientFromResponse(resp)
+	codec := cli.getResponseCodec(resp.Header.Get("Content-Type"))
+	if err := codec.Unmarshal(data, out); err != nil {
+		return nil, resp, err
 	}
 
 	return out, resp, nil
@@ -55,6 +76,7 @@ func ToObject[T any](resp *http.Response, err error) (*T, *http.Response, error)
 //	// Response handling
 func ToString(resp *http.Response, err error) (string, *http.Response, error) {
 	if err != nil {
+		closeResponseBody(resp)
 		return "", nil, err
 	}
 	if resp == nil || resp.Body == nil {
@@ -70,3 +92,86 @@ func ToString(resp *http.Response, err error) (string, *http.Response, error) {
 
 	return string(data), resp, nil
 }
+
+// ToNDJSON decodes the response body as the newline-delimited JSON (NDJSON) stream line by line,
+// and calls the function with the object of every line without reading the whole body into
+// memory. The empty lines are ignored, and a line that can't be decoded is reported as a
+// `*LineError` with the line number. It stops and returns the error if the function returns an
+// error. The method will close the body of the response that after read or stopped.
+//
+//	resp, err := request.GET("https://example.com/logs")
+//	resp, err = request.ToNDJSON(resp, err, func(log *Log) error {
+//	  // Object handling
+//	  return nil
+//	}, request.NDJSONOptions{
+//	  OnLineError: func(err *request.LineError) error {
+//	    log.Printf("skip invalid line: %v", err)
+//	    return nil
+//	  },
+//	})
+func ToNDJSON[T any](
+	resp *http.Response,
+	err error,
+	fn func(*T) error,
+	opts ...NDJSONOptions,
+) (*http.Response, error) {
+	if err != nil {
+		closeResponseBody(resp)
+		return nil, err
+	}
+	if resp == nil || resp.Body == nil {
+		return nil, ErrInvalidResp
+	}
+
+	defer resp.Body.Close()
+
+	var opt NDJSONOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	maxLineSize := opt.MaxLineSize
+	if maxLineSize <= 0 {
+		maxLineSize = defaultNDJSONMaxLineSize
+	}
+
+	// the initial buffer can't be larger than the limit, or the limit will be the buffer size.
+	bufSize := 4096
+	if bufSize > maxLineSize {
+		bufSize = maxLineSize
+	}
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, bufSize), maxLineSize)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		obj := new(T)
+		if err := json.Unmarshal(data, obj); err != nil {
+			lineErr := &LineError{Line: line, Err: err}
+			if opt.OnLineError == nil {
+				return resp, lineErr
+			} else if err := opt.OnLineError(lineErr); err != nil {
+				return resp, err
+			}
+			continue
+		}
+
+		if err := fn(obj); err != nil {
+			return resp, err
+		}
+	}
+
+	if err := scanner.Err(); err == bufio.ErrTooLong {
+		return resp, &LineError{Line: line + 1, Err: err}
+	} else if err != nil {
+		return resp, err
+	}
+
+	return resp, nil
+}
@@ -1,6 +1,7 @@
 package request
 
 import (
+	"bufio"
 	"bytes"
 	"errors"
 	"io"
@@ -41,6 +42,34 @@ func TestToObject(t *testing.T) {
 	a.EqualNow(*data.Method, "GET")
 }
 
+func TestToObjectWithXML(t *testing.T) {
+	a := assert.New(t)
+
+	type testXMLResponse struct {
+		Method string `xml:"method"`
+	}
+
+	data, _, err := ToObject[testXMLResponse](&http.Response{
+		Header: http.Header{"Content-Type": {"application/xml; charset=utf-8"}},
+		Body:   io.NopCloser(bytes.NewReader([]byte(`<resp><method>GET</method></resp>`))),
+	}, nil)
+	a.NilNow(err)
+	a.EqualNow(data.Method, "GET")
+
+	data, _, err = ToObject[testXMLResponse](&http.Response{
+		Header: http.Header{"Content-Type": {"text/xml"}},
+		Body:   io.NopCloser(bytes.NewReader([]byte(`<resp><method>POST</method></resp>`))),
+	}, nil)
+	a.NilNow(err)
+	a.EqualNow(data.Method, "POST")
+
+	_, _, err = ToObject[testXMLResponse](&http.Response{
+		Header: http.Header{"Content-Type": {"text/xml"}},
+		Body:   io.NopCloser(bytes.NewReader([]byte(`{"method":"GET"}`))),
+	}, nil)
+	a.NotNilNow(err)
+}
+
 func TestToString(t *testing.T) {
 	a := assert.New(t)
 
@@ -59,3 +88,93 @@ func TestToString(t *testing.T) {
 	a.NilNow(err)
 	a.EqualNow(data, "Hello world!")
 }
+
+func TestToNDJSON(t *testing.T) {
+	a := assert.New(t)
+
+	count := 0
+	resp, err := GET("http://127.0.0.1:8080/stream?ndjson=1&count=1000")
+	resp, err = ToNDJSON(resp, err, func(elem *testStreamElement) error {
+		a.EqualNow(elem.ID, count)
+		count++
+		return nil
+	})
+	a.NilNow(err)
+	a.NotNilNow(resp)
+	a.EqualNow(count, 1000)
+
+	// stop by the callback, and the body is closed
+	stopErr := errors.New("stop")
+	count = 0
+	resp, err = GET("http://127.0.0.1:8080/stream?ndjson=1&count=1000")
+	resp, err = ToNDJSON(resp, err, func(elem *testStreamElement) error {
+		count++
+		if count == 10 {
+			return stopErr
+		}
+		return nil
+	})
+	a.EqualNow(err, stopErr)
+	a.EqualNow(count, 10)
+	a.NotNilNow(resp.Request.Context().Err())
+
+	// empty lines and CRLF
+	count = 0
+	_, err = ToNDJSON(&http.Response{
+		Body: io.NopCloser(bytes.NewReader([]byte("{\"id\":0}\r\n\r\n  \n{\"id\":1}"))),
+	}, nil, func(elem *testStreamElement) error {
+		a.EqualNow(elem.ID, count)
+		count++
+		return nil
+	})
+	a.NilNow(err)
+	a.EqualNow(count, 2)
+
+	_, err = ToNDJSON[testStreamElement](nil, nil, nil)
+	a.EqualNow(err, ErrInvalidResp)
+}
+
+func TestToNDJSONWithInvalidLines(t *testing.T) {
+	a := assert.New(t)
+
+	count := 0
+	resp, err := GET("http://127.0.0.1:8080/stream?ndjson=1&count=10&invalid=5")
+	_, err = ToNDJSON(resp, err, func(elem *testStreamElement) error {
+		count++
+		return nil
+	})
+	var lineErr *LineError
+	a.TrueNow(errors.As(err, &lineErr))
+	a.EqualNow(lineErr.Line, 6)
+	a.EqualNow(count, 5)
+
+	// skip the invalid lines
+	count = 0
+	lines := make([]int, 0)
+	resp, err = GET("http://127.0.0.1:8080/stream?ndjson=1&count=10&invalid=5")
+	_, err = ToNDJSON(resp, err, func(elem *testStreamElement) error {
+		count++
+		return nil
+	}, NDJSONOptions{
+		OnLineError: func(err *LineError) error {
+			lines = append(lines, err.Line)
+			return nil
+		},
+	})
+	a.NilNow(err)
+	a.EqualNow(count, 10)
+	a.EqualNow(lines, []int{6})
+
+	// the line is too long
+	count = 0
+	resp, err = GET("http://127.0.0.1:8080/stream?ndjson=1&count=100")
+	_, err = ToNDJSON(resp, err, func(elem *testStreamElement) error {
+		count++
+		return nil
+	}, NDJSONOptions{MaxLineSize: 9})
+	a.TrueNow(errors.As(err, &lineErr))
+	a.TrueNow(errors.Is(err, bufio.ErrTooLong))
+	a.EqualNow(lineErr.Line, 11)
+	a.EqualNow(err.Error(), "line 11: bufio.Scanner: token too long")
+	a.EqualNow(count, 10)
+}